@@ -0,0 +1,194 @@
+package certs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// bundleConfigSnippet is the name of the config snippet file inside a bundle
+const bundleConfigSnippet = "config-snippet.yaml"
+
+// bundleFile is a single file included in a certificate bundle
+type bundleFile struct {
+	Name    string
+	Mode    int64
+	Content []byte
+}
+
+// harvesterConfigSnippet is the portion of config.yaml a remote harvester needs to connect to the farmer
+type harvesterConfigSnippet struct {
+	Harvester struct {
+		FarmerPeers []config.Peer `yaml:"farmer_peers"`
+	} `yaml:"harvester"`
+}
+
+// bundleCmd packages the certificates a remote service needs to connect to this node
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Packages the certificates and config a remote harvester needs to connect to this farmer",
+	Example: `chik-tools certs bundle --for harvester --farmer-host 10.0.0.5 --output harvester.tar.gz
+
+# Then, on the harvester machine
+chik-tools certs unbundle harvester.tar.gz`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if viper.GetString("bundle-for") != "harvester" {
+			return fmt.Errorf("unsupported bundle type %s. Only harvester bundles are currently supported", viper.GetString("bundle-for"))
+		}
+		if viper.GetString("bundle-farmer-host") == "" {
+			return fmt.Errorf("must provide the host the harvester should use to reach this farmer with --farmer-host")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		chikRoot, err := config.GetChikRootPath()
+		if err != nil {
+			slogs.Logr.Fatal("Unable to determine CHIK_ROOT", "error", err)
+		}
+		sslDir := path.Join(chikRoot, "config", "ssl")
+
+		privateCACert, privateCAKey, err := loadPrivateCA(path.Join(sslDir, "ca"))
+		if err != nil {
+			slogs.Logr.Fatal("error loading private CA", "error", err)
+		}
+
+		farmerPort := viper.GetUint16("bundle-farmer-port")
+		if farmerPort == 0 {
+			cfg, err := config.GetChikConfig()
+			if err != nil {
+				slogs.Logr.Fatal("error loading config", "error", err)
+			}
+			farmerPort = cfg.Farmer.Port
+		}
+
+		files, err := harvesterBundleFiles(privateCACert, privateCAKey, config.Peer{
+			Host: viper.GetString("bundle-farmer-host"),
+			Port: farmerPort,
+		}, certOptionsFromViper())
+		if err != nil {
+			slogs.Logr.Fatal("error creating harvester bundle", "error", err)
+		}
+
+		output := viper.GetString("bundle-output")
+		err = writeTarGz(output, files)
+		if err != nil {
+			slogs.Logr.Fatal("error writing bundle", "error", err)
+		}
+		slogs.Logr.Info("wrote harvester bundle", "output", output)
+	},
+}
+
+// harvesterBundleFiles generates the private node certificates signed by the private CA and returns all files for the bundle
+func harvesterBundleFiles(privateCACert *x509.Certificate, privateCAKey *rsa.PrivateKey, farmer config.Peer, opts certOptions) ([]bundleFile, error) {
+	tmpDir, err := os.MkdirTemp("", "chik-tools-bundle-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	err = generateAllCerts(tmpDir, privateCACert, privateCAKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error generating certificates: %w", err)
+	}
+
+	// Every private certificate on the harvester host must be signed by the farmer's private CA once it is installed,
+	// otherwise the daemon and other local services can't talk to the harvester. This matches chik init -c
+	names := []string{"ca/private_ca.crt", "ca/chik_ca.crt", "ca/chik_ca.key"}
+	nodeFiles, err := privateNodeFiles(tmpDir)
+	if err != nil {
+		return nil, fmt.Errorf("error finding generated certificates: %w", err)
+	}
+	for _, file := range nodeFiles {
+		names = append(names, filepath.ToSlash(file))
+	}
+
+	var files []bundleFile
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("error reading generated %s: %w", name, err)
+		}
+		mode := int64(0644)
+		if filepath.Ext(name) == ".key" {
+			mode = 0600
+		}
+		files = append(files, bundleFile{Name: path.Join("ssl", name), Mode: mode, Content: content})
+	}
+
+	snippet := harvesterConfigSnippet{}
+	snippet.Harvester.FarmerPeers = []config.Peer{farmer}
+	snippetBytes, err := yaml.Marshal(snippet)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling config snippet: %w", err)
+	}
+	files = append(files, bundleFile{Name: bundleConfigSnippet, Mode: 0644, Content: snippetBytes})
+
+	return files, nil
+}
+
+// writeTarGz writes the files to a gzipped tarball at outPath
+func writeTarGz(outPath string, files []bundleFile) error {
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    file.Name,
+			Mode:    file.Mode,
+			Size:    int64(len(file.Content)),
+			ModTime: now,
+		})
+		if err != nil {
+			return fmt.Errorf("error writing header for %s: %w", file.Name, err)
+		}
+		if _, err = tw.Write(file.Content); err != nil {
+			return fmt.Errorf("error writing %s: %w", file.Name, err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return fmt.Errorf("error closing tar writer: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return fmt.Errorf("error closing gzip writer: %w", err)
+	}
+
+	return out.Close()
+}
+
+func init() {
+	bundleCmd.PersistentFlags().String("for", "harvester", "The type of remote service to create the bundle for")
+	bundleCmd.PersistentFlags().String("farmer-host", "", "The host or IP the harvester should use to connect to this farmer")
+	bundleCmd.PersistentFlags().Uint16("farmer-port", 0, "The port the harvester should use to connect to this farmer (default is the farmer port from config)")
+	bundleCmd.PersistentFlags().StringP("output", "o", "harvester.tar.gz", "Output file for the bundle")
+
+	cobra.CheckErr(viper.BindPFlag("bundle-for", bundleCmd.PersistentFlags().Lookup("for")))
+	cobra.CheckErr(viper.BindPFlag("bundle-farmer-host", bundleCmd.PersistentFlags().Lookup("farmer-host")))
+	cobra.CheckErr(viper.BindPFlag("bundle-farmer-port", bundleCmd.PersistentFlags().Lookup("farmer-port")))
+	cobra.CheckErr(viper.BindPFlag("bundle-output", bundleCmd.PersistentFlags().Lookup("output")))
+
+	addCertOptionFlags(bundleCmd)
+
+	certsCmd.AddCommand(bundleCmd)
+}
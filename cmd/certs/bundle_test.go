@@ -0,0 +1,60 @@
+package certs
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func TestUnbundleSignsEveryPrivateCertWithFarmerCA(t *testing.T) {
+	cmd.InitLogs()
+	opts := certOptionsFromViper()
+
+	// The harvester host starts with certificates from its own chik init
+	configDir := t.TempDir()
+	sslDir := filepath.Join(configDir, "ssl")
+	assert.NoError(t, generateAllCerts(sslDir, nil, nil, opts))
+
+	farmerCACert, farmerCAKey, err := generateCA(opts)
+	assert.NoError(t, err)
+	files, err := harvesterBundleFiles(farmerCACert, farmerCAKey, config.Peer{Host: "10.0.0.5", Port: 8447}, opts)
+	assert.NoError(t, err)
+
+	var sslFiles []bundleFile
+	for _, file := range files {
+		if strings.HasPrefix(file.Name, "ssl/") {
+			sslFiles = append(sslFiles, file)
+		}
+	}
+	assert.NoError(t, installSSLFiles(configDir, sslFiles))
+
+	installedCA, err := loadCertificate(filepath.Join(sslDir, "ca", "private_ca.crt"))
+	assert.NoError(t, err)
+	assert.True(t, farmerCACert.Equal(installedCA))
+	roots := x509.NewCertPool()
+	roots.AddCert(installedCA)
+
+	var checked int
+	err = filepath.WalkDir(sslDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasPrefix(d.Name(), "private_") || filepath.Ext(p) != ".crt" || d.Name() == "private_ca.crt" {
+			return err
+		}
+		cert, err := loadCertificate(p)
+		assert.NoError(t, err)
+		_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		assert.NoError(t, err, d.Name())
+		checked++
+		return nil
+	})
+	assert.NoError(t, err)
+	// Every private node certificate was checked, which is every one go-chik-libs generates
+	assert.Equal(t, 8, checked)
+	assert.NoFileExists(t, filepath.Join(sslDir, "ca", "private_ca.key"))
+}
@@ -0,0 +1,239 @@
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/tls"
+	"github.com/spf13/viper"
+)
+
+// certOptions controls the parameters used when generating CA and node certificates
+type certOptions struct {
+	CAKeySize          int
+	KeySize            int
+	CAValidityDays     int
+	ValidityDays       int
+	Organization       string
+	OrganizationalUnit string
+	CACommonName       string
+	CommonName         string
+	DNSName            string
+}
+
+// defaultCertOptions match the certificates go-chik-libs generates, which are the same as chik init
+var defaultCertOptions = certOptions{
+	CAKeySize:          2048,
+	KeySize:            2048,
+	CAValidityDays:     3650,
+	Organization:       "Chik",
+	OrganizationalUnit: "Organic Farming Division",
+	CACommonName:       "Chik CA",
+	CommonName:         "Chik",
+	DNSName:            "chiknetwork.com",
+}
+
+// certOptionsFromViper reads the certificate options from flags or the config file
+func certOptionsFromViper() certOptions {
+	return certOptions{
+		CAKeySize:          viper.GetInt("cert-ca-key-size"),
+		KeySize:            viper.GetInt("cert-key-size"),
+		CAValidityDays:     viper.GetInt("cert-ca-validity-days"),
+		ValidityDays:       viper.GetInt("cert-validity-days"),
+		Organization:       viper.GetString("cert-organization"),
+		OrganizationalUnit: viper.GetString("cert-organizational-unit"),
+		CACommonName:       viper.GetString("cert-ca-common-name"),
+		CommonName:         viper.GetString("cert-common-name"),
+		DNSName:            viper.GetString("cert-dns-name"),
+	}
+}
+
+// validate ensures the options will produce usable certificates
+func (o certOptions) validate() error {
+	if o.CAKeySize < 2048 || o.KeySize < 2048 {
+		return fmt.Errorf("key sizes must be at least 2048 bits")
+	}
+	if o.CAValidityDays < 1 {
+		return fmt.Errorf("CA validity must be at least 1 day")
+	}
+	if o.ValidityDays < 0 {
+		return fmt.Errorf("validity days can not be negative")
+	}
+	return nil
+}
+
+// customized returns true when the options ask for certificates that differ from the ones go-chik-libs generates
+func (o certOptions) customized() bool {
+	return o != defaultCertOptions
+}
+
+// generateCA generates a new self-signed private CA with go-chik-libs, reissued with the options when they are customized
+func generateCA(opts certOptions) (*x509.Certificate, *rsa.PrivateKey, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	cert, key, err := tls.GenerateNewCA()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating CA: %w", err)
+	}
+	if !opts.customized() {
+		return cert, key, nil
+	}
+	return reissueCert(cert, nil, nil, opts)
+}
+
+// generateAllCerts writes a full ssl tree to outDir with go-chik-libs, in the same layout as chik init
+// A new private CA is generated when privateCACert or privateCAKey is nil
+// When the options are customized, every CA and node certificate the library generated is reissued with them
+func generateAllCerts(outDir string, privateCACert *x509.Certificate, privateCAKey *rsa.PrivateKey, opts certOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	if opts.customized() && (privateCACert == nil || privateCAKey == nil) {
+		var err error
+		privateCACert, privateCAKey, err = generateCA(opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tls.GenerateAndWriteAllCerts(outDir, privateCACert, privateCAKey)
+	if err != nil {
+		return err
+	}
+	if !opts.customized() {
+		return nil
+	}
+
+	publicCACertBytes, publicCAKeyBytes := tls.GetChikCACertAndKey()
+	publicCACert, err := tls.ParsePemCertificate(publicCACertBytes)
+	if err != nil {
+		return fmt.Errorf("error parsing chik CA certificate: %w", err)
+	}
+	publicCAKey, err := tls.ParsePemKey(publicCAKeyBytes)
+	if err != nil {
+		return fmt.Errorf("error parsing chik CA key: %w", err)
+	}
+
+	return filepath.WalkDir(outDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(outDir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Dir(relPath) == "ca" || filepath.Ext(p) != ".crt" {
+			return nil
+		}
+
+		parent, parentKey := privateCACert, privateCAKey
+		if strings.HasPrefix(d.Name(), "public_") {
+			parent, parentKey = publicCACert, publicCAKey
+		}
+		generated, err := loadCertificate(p)
+		if err != nil {
+			return fmt.Errorf("error reading generated %s: %w", relPath, err)
+		}
+		cert, key, err := reissueCert(generated, parent, parentKey, opts)
+		if err != nil {
+			return fmt.Errorf("error reissuing %s: %w", relPath, err)
+		}
+		return writeCertAndKey(cert, key, strings.TrimSuffix(p, ".crt"))
+	})
+}
+
+// reissueCert issues a copy of a certificate go-chik-libs generated with a new key, and the key size, validity and
+// subject from the options. Everything else, such as the extensions and SANs, comes from the library's certificate
+// A nil parent self-signs the copy, which is how the private CA is reissued
+func reissueCert(generated *x509.Certificate, parent *x509.Certificate, parentKey *rsa.PrivateKey, opts certOptions) (*x509.Certificate, *rsa.PrivateKey, error) {
+	keySize, validityDays, commonName := opts.KeySize, opts.ValidityDays, opts.CommonName
+	if generated.IsCA {
+		keySize, validityDays, commonName = opts.CAKeySize, opts.CAValidityDays, opts.CACommonName
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := *generated
+	template.Raw, template.RawTBSCertificate, template.RawSubjectPublicKeyInfo = nil, nil, nil
+	template.RawSubject, template.RawIssuer = nil, nil
+	template.PublicKey, template.Signature = nil, nil
+	template.SubjectKeyId, template.AuthorityKeyId = nil, nil
+	template.SerialNumber = serial
+	template.Subject.CommonName = commonName
+	template.Subject.Organization = []string{opts.Organization}
+	template.Subject.OrganizationalUnit = []string{opts.OrganizationalUnit}
+	template.Subject.Names = nil
+	if validityDays > 0 {
+		template.NotAfter = time.Now().AddDate(0, 0, validityDays)
+	}
+	if len(template.DNSNames) > 0 {
+		template.DNSNames = []string{opts.DNSName}
+	}
+
+	if parent == nil {
+		parent, parentKey = &template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing certificate: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// writeCertAndKey PEM encodes the cert and key and writes them to base.crt and base.key
+func writeCertAndKey(cert *x509.Certificate, key *rsa.PrivateKey, base string) error {
+	certPEM, keyPEM, err := tls.EncodeCertAndKeyToPEM(cert, key)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", filepath.Base(base), err)
+	}
+	return writePEMPair(certPEM, keyPEM, base)
+}
+
+// writePEMPair writes already encoded PEM bytes to base.crt and base.key with the permissions chik expects
+func writePEMPair(certPEM, keyPEM []byte, base string) error {
+	err := os.MkdirAll(filepath.Dir(base), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", filepath.Base(base), err)
+	}
+	err = os.WriteFile(base+".crt", certPEM, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing %s.crt: %w", filepath.Base(base), err)
+	}
+	err = os.WriteFile(base+".key", keyPEM, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing %s.key: %w", filepath.Base(base), err)
+	}
+	// WriteFile doesn't change the permissions of existing files
+	return os.Chmod(base+".key", 0600)
+}
+
+// randomSerial returns a random 128 bit certificate serial number
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %w", err)
+	}
+	return serial, nil
+}
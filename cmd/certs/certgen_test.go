@@ -0,0 +1,141 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/tls"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func TestGenerateAllCerts_Options(t *testing.T) {
+	cmd.InitLogs()
+	opts := certOptionsFromViper()
+	opts.CAKeySize = 4096
+	opts.ValidityDays = 30
+	opts.Organization = "Example Org"
+	opts.CommonName = "example-node"
+
+	sslDir := t.TempDir()
+	err := generateAllCerts(sslDir, nil, nil, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, failedChecks(verifySSLDir(sslDir)))
+
+	caCert, err := loadCertificate(filepath.Join(sslDir, "ca", "private_ca.crt"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4096, caCert.PublicKey.(*rsa.PublicKey).N.BitLen())
+	assert.Equal(t, []string{"Example Org"}, caCert.Subject.Organization)
+
+	nodeCert, err := loadCertificate(filepath.Join(sslDir, "full_node", "private_full_node.crt"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, nodeCert.PublicKey.(*rsa.PublicKey).N.BitLen())
+	assert.Equal(t, "example-node", nodeCert.Subject.CommonName)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), nodeCert.NotAfter, time.Hour)
+}
+
+func TestGenerateAllCerts_ExistingCA(t *testing.T) {
+	cmd.InitLogs()
+	opts := certOptionsFromViper()
+	caCert, caKey, err := generateCA(opts)
+	assert.NoError(t, err)
+
+	sslDir := t.TempDir()
+	err = generateAllCerts(sslDir, caCert, caKey, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, failedChecks(verifySSLDir(sslDir)))
+
+	writtenCA, err := loadCertificate(filepath.Join(sslDir, "ca", "private_ca.crt"))
+	assert.NoError(t, err)
+	assert.True(t, caCert.Equal(writtenCA))
+}
+
+func TestCertOptionsValidate(t *testing.T) {
+	opts := certOptionsFromViper()
+	assert.NoError(t, opts.validate())
+
+	opts.KeySize = 1024
+	assert.Error(t, opts.validate())
+}
+
+// TestGenerateAllCerts_Reissued checks that customized certificates keep everything the options don't change from go-chik-libs
+func TestGenerateAllCerts_Reissued(t *testing.T) {
+	cmd.InitLogs()
+	libDir := t.TempDir()
+	assert.NoError(t, tls.GenerateAndWriteAllCerts(libDir, nil, nil))
+	opts := defaultCertOptions
+	opts.KeySize = 3072
+	opts.DNSName = "chik.example.com"
+	ourDir := t.TempDir()
+	assert.NoError(t, generateAllCerts(ourDir, nil, nil, opts))
+	assert.Empty(t, failedChecks(verifySSLDir(ourDir)))
+
+	libCerts := certsByRelativePath(t, libDir)
+	ourCerts := certsByRelativePath(t, ourDir)
+	assert.NotEmpty(t, libCerts)
+
+	var libPaths, ourPaths []string
+	for p := range libCerts {
+		libPaths = append(libPaths, p)
+	}
+	for p := range ourCerts {
+		ourPaths = append(ourPaths, p)
+	}
+	assert.ElementsMatch(t, libPaths, ourPaths)
+
+	for p, libCert := range libCerts {
+		ourCert, ok := ourCerts[p]
+		if !ok {
+			continue
+		}
+		assert.Equal(t, libCert.IsCA, ourCert.IsCA, p)
+		assert.Equal(t, libCert.BasicConstraintsValid, ourCert.BasicConstraintsValid, p)
+		assert.Equal(t, libCert.KeyUsage, ourCert.KeyUsage, p)
+		assert.Equal(t, libCert.ExtKeyUsage, ourCert.ExtKeyUsage, p)
+		assert.Equal(t, libCert.SignatureAlgorithm, ourCert.SignatureAlgorithm, p)
+		assert.Equal(t, libCert.Subject.String(), ourCert.Subject.String(), p)
+
+		// The public chik CA is shipped with chik and is copied, not generated
+		if strings.HasPrefix(filepath.Base(p), "chik_ca") {
+			assert.True(t, libCert.Equal(ourCert), p)
+			continue
+		}
+		if libCert.IsCA {
+			assert.WithinDuration(t, libCert.NotAfter, ourCert.NotAfter, 48*time.Hour, p)
+			continue
+		}
+		assert.Equal(t, 3072, ourCert.PublicKey.(*rsa.PublicKey).N.BitLen(), p)
+		assert.Equal(t, libCert.NotAfter, ourCert.NotAfter, p)
+		if len(libCert.DNSNames) > 0 {
+			assert.Equal(t, []string{"chik.example.com"}, ourCert.DNSNames, p)
+		}
+	}
+}
+
+// certsByRelativePath loads every certificate in an ssl directory keyed by its path relative to the directory
+func certsByRelativePath(t *testing.T, sslDir string) map[string]*x509.Certificate {
+	certs := map[string]*x509.Certificate{}
+	err := filepath.WalkDir(sslDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".crt" {
+			return err
+		}
+		cert, err := loadCertificate(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(sslDir, p)
+		if err != nil {
+			return err
+		}
+		certs[rel] = cert
+		return nil
+	})
+	assert.NoError(t, err)
+	return certs
+}
@@ -2,16 +2,71 @@ package certs
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/chik-network/chik-tools/cmd"
 )
 
+// certOptionKeys maps the flags for settings of newly generated certificates to their viper keys
+var certOptionKeys = map[string]string{
+	"ca-key-size":         "cert-ca-key-size",
+	"key-size":            "cert-key-size",
+	"ca-validity-days":    "cert-ca-validity-days",
+	"validity-days":       "cert-validity-days",
+	"organization":        "cert-organization",
+	"organizational-unit": "cert-organizational-unit",
+	"ca-common-name":      "cert-ca-common-name",
+	"common-name":         "cert-common-name",
+	"dns-name":            "cert-dns-name",
+}
+
 // certsCmd represents the config command
 var certsCmd = &cobra.Command{
 	Use:   "certs",
 	Short: "Utilities for working with chik certificates",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindCertOptionFlags(cmd)
+	},
+}
+
+// addCertOptionFlags adds the settings for newly generated certificates to a command that generates certificates
+// These can also be set in the chik-tools config file using the viper key names
+func addCertOptionFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Int("ca-key-size", defaultCertOptions.CAKeySize, "RSA key size in bits for generated CA keys")
+	cmd.PersistentFlags().Int("key-size", defaultCertOptions.KeySize, "RSA key size in bits for generated node keys")
+	cmd.PersistentFlags().Int("ca-validity-days", defaultCertOptions.CAValidityDays, "Number of days generated CA certificates are valid for")
+	cmd.PersistentFlags().Int("validity-days", defaultCertOptions.ValidityDays, "Number of days generated node certificates are valid for. 0 uses the chik default expiry of 2100-08-02")
+	cmd.PersistentFlags().String("organization", defaultCertOptions.Organization, "Organization in the subject of generated certificates")
+	cmd.PersistentFlags().String("organizational-unit", defaultCertOptions.OrganizationalUnit, "Organizational unit in the subject of generated certificates")
+	cmd.PersistentFlags().String("ca-common-name", defaultCertOptions.CACommonName, "Common name in the subject of generated CA certificates")
+	cmd.PersistentFlags().String("common-name", defaultCertOptions.CommonName, "Common name in the subject of generated node certificates")
+	cmd.PersistentFlags().String("dns-name", defaultCertOptions.DNSName, "DNS subject alternative name of generated node certificates")
+}
+
+// bindCertOptionFlags binds the certificate settings of the command being run to viper
+// Several commands share the same viper keys, so this happens when the command runs instead of in init
+func bindCertOptionFlags(cmd *cobra.Command) error {
+	for flag, key := range certOptionKeys {
+		if f := cmd.Flags().Lookup(flag); f != nil {
+			if err := viper.BindPFlag(key, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func init() {
+	// Defaults for when the settings are read without a command that has the flags
+	viper.SetDefault("cert-ca-key-size", defaultCertOptions.CAKeySize)
+	viper.SetDefault("cert-key-size", defaultCertOptions.KeySize)
+	viper.SetDefault("cert-ca-validity-days", defaultCertOptions.CAValidityDays)
+	viper.SetDefault("cert-validity-days", defaultCertOptions.ValidityDays)
+	viper.SetDefault("cert-organization", defaultCertOptions.Organization)
+	viper.SetDefault("cert-organizational-unit", defaultCertOptions.OrganizationalUnit)
+	viper.SetDefault("cert-ca-common-name", defaultCertOptions.CACommonName)
+	viper.SetDefault("cert-common-name", defaultCertOptions.CommonName)
+	viper.SetDefault("cert-dns-name", defaultCertOptions.DNSName)
+
 	cmd.RootCmd.AddCommand(certsCmd)
 }
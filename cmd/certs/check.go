@@ -0,0 +1,246 @@
+package certs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Nagios style status codes used as the exit code for the check command
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStatusNames = map[int]string{
+	checkOK:       "OK",
+	checkWarning:  "WARNING",
+	checkCritical: "CRITICAL",
+	checkUnknown:  "UNKNOWN",
+}
+
+// certificateExpiry is the remaining lifetime of a single certificate
+type certificateExpiry struct {
+	Path      string
+	NotAfter  time.Time
+	Remaining time.Duration
+	Status    int
+	Error     error
+}
+
+// checkCmd checks certificates for upcoming expiry
+var checkCmd = &cobra.Command{
+	Use:   "check [ssl-dir]",
+	Short: "Checks certificates for upcoming expiry, exiting with nagios style status codes",
+	Example: `# Check all certificates referenced in config.yaml
+chik-tools certs check --warn-days 30 --crit-days 7
+
+# Check all certificates in a directory
+chik-tools certs check ~/.chik/mainnet/config/ssl
+
+# Output metrics for the prometheus node exporter textfile collector
+chik-tools certs check --prometheus > /var/lib/node_exporter/textfile/chik_certs.prom`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var certPaths []string
+		var err error
+		if len(args) > 0 {
+			certPaths, err = collectCertificatePaths(args)
+		} else {
+			certPaths, err = configCertificatePaths(viper.GetString("check-config"))
+		}
+		if err != nil {
+			slogs.Logr.Error("error finding certificates", "error", err)
+			os.Exit(checkUnknown)
+		}
+
+		warn := time.Duration(viper.GetInt("check-warn-days")) * 24 * time.Hour
+		crit := time.Duration(viper.GetInt("check-crit-days")) * 24 * time.Hour
+		expiries := checkCertificateExpiry(certPaths, time.Now(), warn, crit)
+
+		if viper.GetBool("check-prometheus") {
+			writePrometheusExpiry(os.Stdout, expiries)
+		} else {
+			writeExpiryTable(os.Stdout, expiries)
+		}
+
+		os.Exit(overallCheckStatus(expiries))
+	},
+}
+
+// configCertificatePaths returns the absolute path of every unique certificate referenced in config.yaml
+func configCertificatePaths(cfgPath string) ([]string, error) {
+	chikRoot, paths, err := configSSLPaths(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := map[string]bool{}
+	for _, referenced := range paths {
+		if filepath.Ext(referenced) != ".crt" {
+			continue
+		}
+		if !filepath.IsAbs(referenced) {
+			referenced = filepath.Join(chikRoot, referenced)
+		}
+		unique[referenced] = true
+	}
+
+	var certPaths []string
+	for certPath := range unique {
+		certPaths = append(certPaths, certPath)
+	}
+	sort.Strings(certPaths)
+
+	return certPaths, nil
+}
+
+// checkCertificateExpiry loads each certificate and determines its status against the thresholds
+func checkCertificateExpiry(certPaths []string, now time.Time, warn, crit time.Duration) []certificateExpiry {
+	var expiries []certificateExpiry
+	for _, certPath := range certPaths {
+		expiry := certificateExpiry{Path: certPath, Status: checkOK}
+		cert, err := loadCertificate(certPath)
+		if err != nil {
+			expiry.Status = checkUnknown
+			expiry.Error = err
+			expiries = append(expiries, expiry)
+			continue
+		}
+
+		expiry.NotAfter = cert.NotAfter
+		expiry.Remaining = cert.NotAfter.Sub(now)
+		if expiry.Remaining <= crit {
+			expiry.Status = checkCritical
+		} else if expiry.Remaining <= warn {
+			expiry.Status = checkWarning
+		}
+		expiries = append(expiries, expiry)
+	}
+
+	return expiries
+}
+
+// CertificateStatus is the expiry status of a certificate referenced in config.yaml
+type CertificateStatus struct {
+	Path     string    `json:"path"`
+	Status   string    `json:"status"`
+	NotAfter time.Time `json:"not_after"`
+	DaysLeft int       `json:"days_left"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ConfigCertificateStatus checks every certificate referenced in the config.yaml in CHIK_ROOT against the thresholds
+func ConfigCertificateStatus(warn, crit time.Duration) ([]CertificateStatus, error) {
+	certPaths, err := configCertificatePaths("")
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []CertificateStatus
+	for _, expiry := range checkCertificateExpiry(certPaths, time.Now(), warn, crit) {
+		status := CertificateStatus{
+			Path:     expiry.Path,
+			Status:   checkStatusNames[expiry.Status],
+			NotAfter: expiry.NotAfter,
+			DaysLeft: int(expiry.Remaining.Hours() / 24),
+		}
+		if expiry.Error != nil {
+			status.Error = expiry.Error.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// overallCheckStatus returns the most severe status, where critical and warning take precedence over unknown
+func overallCheckStatus(expiries []certificateExpiry) int {
+	status := checkOK
+	for _, expiry := range expiries {
+		switch {
+		case expiry.Status == checkCritical:
+			return checkCritical
+		case expiry.Status == checkWarning:
+			status = checkWarning
+		case expiry.Status == checkUnknown && status == checkOK:
+			status = checkUnknown
+		}
+	}
+	return status
+}
+
+// writeExpiryTable writes a human readable table of the remaining lifetime of each certificate
+func writeExpiryTable(out io.Writer, expiries []certificateExpiry) {
+	w := tabwriter.NewWriter(out, 1, 1, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STATUS\tDAYS LEFT\tNOT AFTER\tPATH")
+	for _, expiry := range expiries {
+		if expiry.Error != nil {
+			_, _ = fmt.Fprintf(w, "%s\t-\t-\t%s (%s)\n", checkStatusNames[expiry.Status], expiry.Path, expiry.Error.Error())
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n",
+			checkStatusNames[expiry.Status],
+			int(expiry.Remaining.Hours()/24),
+			expiry.NotAfter.Format(time.DateOnly),
+			expiry.Path)
+	}
+	_ = w.Flush()
+}
+
+// writePrometheusExpiry writes the expiry information in the prometheus text exposition format
+func writePrometheusExpiry(out io.Writer, expiries []certificateExpiry) {
+	_, _ = fmt.Fprintln(out, "# HELP chik_certificate_expiry_timestamp_seconds Time the certificate expires, as a unix timestamp")
+	_, _ = fmt.Fprintln(out, "# TYPE chik_certificate_expiry_timestamp_seconds gauge")
+	for _, expiry := range expiries {
+		if expiry.Error == nil {
+			_, _ = fmt.Fprintf(out, "chik_certificate_expiry_timestamp_seconds{path=\"%s\"} %d\n", prometheusLabel(expiry.Path), expiry.NotAfter.Unix())
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, "# HELP chik_certificate_remaining_seconds Seconds until the certificate expires")
+	_, _ = fmt.Fprintln(out, "# TYPE chik_certificate_remaining_seconds gauge")
+	for _, expiry := range expiries {
+		if expiry.Error == nil {
+			_, _ = fmt.Fprintf(out, "chik_certificate_remaining_seconds{path=\"%s\"} %.0f\n", prometheusLabel(expiry.Path), expiry.Remaining.Seconds())
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, "# HELP chik_certificate_check_status Nagios style status of the certificate. 0=OK 1=WARNING 2=CRITICAL 3=UNKNOWN")
+	_, _ = fmt.Fprintln(out, "# TYPE chik_certificate_check_status gauge")
+	for _, expiry := range expiries {
+		_, _ = fmt.Fprintf(out, "chik_certificate_check_status{path=\"%s\"} %d\n", prometheusLabel(expiry.Path), expiry.Status)
+	}
+}
+
+// prometheusLabelEscaper escapes label values following the text exposition format
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusLabel escapes a label value. Only backslash, double quote and newline are escaped in the exposition format
+func prometheusLabel(value string) string {
+	return prometheusLabelEscaper.Replace(value)
+}
+
+func init() {
+	checkCmd.PersistentFlags().Int("warn-days", 30, "Exit with a warning status when a certificate expires within this many days")
+	checkCmd.PersistentFlags().Int("crit-days", 7, "Exit with a critical status when a certificate expires within this many days")
+	checkCmd.PersistentFlags().Bool("prometheus", false, "Output in the prometheus text format instead of a table")
+	checkCmd.PersistentFlags().String("config", "", "existing config file to read certificate paths from (default is to look in $CHIK_ROOT)")
+
+	cobra.CheckErr(viper.BindPFlag("check-warn-days", checkCmd.PersistentFlags().Lookup("warn-days")))
+	cobra.CheckErr(viper.BindPFlag("check-crit-days", checkCmd.PersistentFlags().Lookup("crit-days")))
+	cobra.CheckErr(viper.BindPFlag("check-prometheus", checkCmd.PersistentFlags().Lookup("prometheus")))
+	cobra.CheckErr(viper.BindPFlag("check-config", checkCmd.PersistentFlags().Lookup("config")))
+
+	certsCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,49 @@
+package certs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverallCheckStatus(t *testing.T) {
+	status := func(statuses ...int) int {
+		var expiries []certificateExpiry
+		for _, s := range statuses {
+			expiries = append(expiries, certificateExpiry{Status: s})
+		}
+		return overallCheckStatus(expiries)
+	}
+
+	assert.Equal(t, checkOK, status())
+	assert.Equal(t, checkOK, status(checkOK, checkOK))
+	assert.Equal(t, checkUnknown, status(checkOK, checkUnknown))
+	assert.Equal(t, checkWarning, status(checkUnknown, checkWarning))
+	assert.Equal(t, checkWarning, status(checkWarning, checkUnknown))
+	assert.Equal(t, checkCritical, status(checkWarning, checkCritical, checkUnknown))
+	assert.Equal(t, checkCritical, status(checkUnknown, checkCritical))
+}
+
+func TestWritePrometheusExpiry(t *testing.T) {
+	notAfter := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	out := &bytes.Buffer{}
+	writePrometheusExpiry(out, []certificateExpiry{
+		{Path: `C:\chik\ssl\"quoted"` + "\nnode.crt", NotAfter: notAfter, Remaining: 90 * time.Second, Status: checkWarning},
+		{Path: "/ssl/missing.crt", Status: checkUnknown, Error: errors.New("not found")},
+	})
+
+	assert.Equal(t, `# HELP chik_certificate_expiry_timestamp_seconds Time the certificate expires, as a unix timestamp
+# TYPE chik_certificate_expiry_timestamp_seconds gauge
+chik_certificate_expiry_timestamp_seconds{path="C:\\chik\\ssl\\\"quoted\"\nnode.crt"} 1893456000
+# HELP chik_certificate_remaining_seconds Seconds until the certificate expires
+# TYPE chik_certificate_remaining_seconds gauge
+chik_certificate_remaining_seconds{path="C:\\chik\\ssl\\\"quoted\"\nnode.crt"} 90
+# HELP chik_certificate_check_status Nagios style status of the certificate. 0=OK 1=WARNING 2=CRITICAL 3=UNKNOWN
+# TYPE chik_certificate_check_status gauge
+chik_certificate_check_status{path="C:\\chik\\ssl\\\"quoted\"\nnode.crt"} 1
+chik_certificate_check_status{path="/ssl/missing.crt"} 3
+`, out.String())
+}
@@ -0,0 +1,139 @@
+package certs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for certificates that aren't written directly to disk
+const (
+	formatK8sSecret = "k8s-secret"
+	formatEnv       = "env"
+)
+
+// envPrefix is prepended to every environment variable name output by the env format
+const envPrefix = "CHIK_SSL_"
+
+var envInvalidChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// k8sSecret is the minimal representation of a kubernetes secret manifest
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sSecretMetadata `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type k8sSecretMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// formatFiles renders the files in one of the secret output formats
+func formatFiles(format string, files []bundleFile, secretName, namespace string) ([]byte, error) {
+	switch format {
+	case formatK8sSecret:
+		return formatAsK8sSecret(files, secretName, namespace)
+	case formatEnv:
+		return formatAsEnv(files), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// formatAsK8sSecret renders the files as a kubernetes secret that can be applied with kubectl
+// Secret keys can't contain slashes, so only the file name is used. File names are unique across a chik ssl tree
+func formatAsK8sSecret(files []bundleFile, secretName, namespace string) ([]byte, error) {
+	secret := k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: k8sSecretMetadata{
+			Name:      secretName,
+			Namespace: namespace,
+		},
+		Type: "Opaque",
+		Data: map[string]string{},
+	}
+	for _, file := range files {
+		key := path.Base(file.Name)
+		if _, exists := secret.Data[key]; exists {
+			return nil, fmt.Errorf("duplicate file name %s can't be represented in a secret", key)
+		}
+		secret.Data[key] = base64.StdEncoding.EncodeToString(file.Content)
+	}
+
+	return yaml.Marshal(secret)
+}
+
+// formatAsEnv renders the files as KEY=base64 lines usable as a docker compose env_file
+func formatAsEnv(files []bundleFile) []byte {
+	var lines []string
+	for _, file := range files {
+		name := strings.ToUpper(path.Base(file.Name))
+		name = envInvalidChars.ReplaceAllString(name, "_")
+		lines = append(lines, fmt.Sprintf("%s%s=%s", envPrefix, name, base64.StdEncoding.EncodeToString(file.Content)))
+	}
+	sort.Strings(lines)
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// readSSLTree reads every file in the ssl directory, optionally limited to the CA certificates and a single service
+func readSSLTree(sslDir string, service string) ([]bundleFile, error) {
+	var files []bundleFile
+	err := filepath.WalkDir(sslDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(sslDir, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if service != "" {
+			dir := path.Dir(relPath)
+			isCACert := dir == "ca" && path.Ext(relPath) == ".crt"
+			if dir != service && !isCACert {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, bundleFile{Name: relPath, Mode: int64(info.Mode().Perm()), Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if service != "" {
+		found := false
+		for _, file := range files {
+			if path.Dir(file.Name) == service {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no certificates found for service %s", service)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,42 @@
+package certs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var testFiles = []bundleFile{
+	{Name: "ca/private_ca.crt", Content: []byte("ca cert")},
+	{Name: "full_node/private_full_node.key", Content: []byte("node key")},
+}
+
+func TestFormatAsEnv(t *testing.T) {
+	output := formatAsEnv(testFiles)
+	assert.Equal(t, "CHIK_SSL_PRIVATE_CA_CRT=Y2EgY2VydA==\nCHIK_SSL_PRIVATE_FULL_NODE_KEY=bm9kZSBrZXk=\n", string(output))
+}
+
+func TestFormatAsK8sSecret(t *testing.T) {
+	output, err := formatAsK8sSecret(testFiles, "chik-ssl", "chik")
+	assert.NoError(t, err)
+
+	secret := k8sSecret{}
+	err = yaml.Unmarshal(output, &secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "Secret", secret.Kind)
+	assert.Equal(t, "chik-ssl", secret.Metadata.Name)
+	assert.Equal(t, "chik", secret.Metadata.Namespace)
+	assert.Equal(t, map[string]string{
+		"private_ca.crt":        "Y2EgY2VydA==",
+		"private_full_node.key": "bm9kZSBrZXk=",
+	}, secret.Data)
+}
+
+func TestFormatAsK8sSecret_DuplicateNames(t *testing.T) {
+	_, err := formatAsK8sSecret([]bundleFile{
+		{Name: "a/private_ca.crt"},
+		{Name: "b/private_ca.crt"},
+	}, "chik-ssl", "")
+	assert.Error(t, err)
+}
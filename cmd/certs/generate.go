@@ -4,10 +4,10 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"os"
 	"path"
 
-	"github.com/chik-network/go-chik-libs/pkg/tls"
 	"github.com/chik-network/go-modules/pkg/slogs"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,64 +15,122 @@ import (
 
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
-	Use:     "generate",
-	Short:   "Generates a full set of certificates for chik-blockchain",
-	Example: "chik-tools certs generate --output ~/.chik/mainnet/config/ssl",
+	Use:   "generate",
+	Short: "Generates a full set of certificates for chik-blockchain",
+	Example: `chik-tools certs generate --output ~/.chik/mainnet/config/ssl
+
+# Use 4096 bit keys and node certificates that expire after one year
+chik-tools certs generate --ca-key-size 4096 --key-size 4096 --validity-days 365 --output ~/.chik/mainnet/config/ssl
+
+# Use the CA output by generate-ca
+chik-tools certs generate-ca > ca.yaml
+chik-tools certs generate --ca-bundle ca.yaml --output ~/.chik/mainnet/config/ssl
+
+# Output the full ssl tree as a kubernetes secret instead of writing files
+chik-tools certs generate --ca ./ca --format k8s-secret --secret-name chik-ssl --namespace chik | kubectl apply -f -
+
+# Output only the certificates the full node needs as environment variables for docker compose
+chik-tools certs generate --ca ./ca --format env --service full_node > full_node.env`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var privateCACert *x509.Certificate
 		var privateCAKey *rsa.PrivateKey
 		caDir := viper.GetString("ca")
 		if caDir != "" {
-			caCertPath := path.Join(caDir, "private_ca.crt")
-			caKeyPath := path.Join(caDir, "private_ca.key")
-
-			if _, err := os.Stat(caCertPath); err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					slogs.Logr.Fatal("private_ca.crt does not exist at the provided path", "path", caCertPath)
-				} else {
-					slogs.Logr.Fatal("error checking private_ca.crt", "error", err)
-				}
-			}
-
-			certBytes, err := os.ReadFile(caCertPath)
-			if err != nil {
-				slogs.Logr.Fatal("error reading ca cert from filesystem", "error", err)
-			}
-			privateCACert, err = tls.ParsePemCertificate(certBytes)
+			var err error
+			privateCACert, privateCAKey, err = loadPrivateCA(caDir)
 			if err != nil {
-				slogs.Logr.Fatal("error parsing certificate", "error", err)
+				slogs.Logr.Fatal("error loading private CA", "error", err)
 			}
-
-			if _, err := os.Stat(caKeyPath); err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					slogs.Logr.Fatal("private_ca.key does not exist at the provided path", "path", caKeyPath)
-				} else {
-					slogs.Logr.Fatal("error checking private_ca.key", "error", err)
-				}
-			}
-
-			keyBytes, err := os.ReadFile(caKeyPath)
+		}
+		if bundlePath := viper.GetString("ca-bundle"); bundlePath != "" {
+			var err error
+			privateCACert, privateCAKey, err = loadCABundle(bundlePath)
 			if err != nil {
-				slogs.Logr.Fatal("error reading ca key from filesystem", "error", err)
+				slogs.Logr.Fatal("error loading private CA from bundle", "error", err)
 			}
-			privateCAKey, err = tls.ParsePemKey(keyBytes)
+		}
+		format := viper.GetString("cert-format")
+		if format == "files" {
+			err := generateAllCerts(viper.GetString("cert-output"), privateCACert, privateCAKey, certOptionsFromViper())
 			if err != nil {
-				slogs.Logr.Fatal("error parsing key", "error", err)
+				slogs.Logr.Fatal("error generating certificates", "error", err)
 			}
+			return
 		}
-		err := tls.GenerateAndWriteAllCerts(viper.GetString("cert-output"), privateCACert, privateCAKey)
+
+		tmpDir, err := os.MkdirTemp("", "chik-tools-generate-*")
+		if err != nil {
+			slogs.Logr.Fatal("error creating temp directory", "error", err)
+		}
+		defer func() {
+			_ = os.RemoveAll(tmpDir)
+		}()
+
+		err = generateAllCerts(tmpDir, privateCACert, privateCAKey, certOptionsFromViper())
 		if err != nil {
 			slogs.Logr.Fatal("error generating certificates", "error", err)
 		}
+		files, err := readSSLTree(tmpDir, viper.GetString("cert-service"))
+		if err != nil {
+			slogs.Logr.Fatal("error reading generated certificates", "error", err)
+		}
+		formatted, err := formatFiles(format, files, viper.GetString("cert-secret-name"), viper.GetString("cert-namespace"))
+		if err != nil {
+			slogs.Logr.Fatal("error formatting certificates", "error", err)
+		}
+		fmt.Print(string(formatted))
 	},
 }
 
+// loadPrivateCA loads the private_ca.crt and private_ca.key files from the given directory
+func loadPrivateCA(caDir string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	caCertPath := path.Join(caDir, "private_ca.crt")
+	caKeyPath := path.Join(caDir, "private_ca.key")
+
+	if _, err := os.Stat(caCertPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("private_ca.crt does not exist at the provided path %s", caCertPath)
+		}
+		return nil, nil, fmt.Errorf("error checking private_ca.crt: %w", err)
+	}
+	privateCACert, err := loadCertificate(caCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading private_ca.crt: %w", err)
+	}
+
+	if _, err := os.Stat(caKeyPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("private_ca.key does not exist at the provided path %s", caKeyPath)
+		}
+		return nil, nil, fmt.Errorf("error checking private_ca.key: %w", err)
+	}
+	privateCAKey, err := loadKey(caKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading private_ca.key: %w", err)
+	}
+
+	return privateCACert, privateCAKey, nil
+}
+
 func init() {
 	generateCmd.PersistentFlags().String("ca", "", "Optionally specify a directory that has an existing private_ca.crt/key")
+	generateCmd.PersistentFlags().String("ca-bundle", "", "Optionally specify a yaml or json file in the format output by generate-ca that contains private_ca.crt/key")
 	generateCmd.PersistentFlags().StringP("output", "o", "certs", "Output directory for certs")
+	generateCmd.PersistentFlags().String("format", "files", "Output format, one of files, k8s-secret, env. Formats other than files are printed to stdout")
+	generateCmd.PersistentFlags().String("secret-name", "chik-ssl", "Name of the secret when using the k8s-secret format")
+	generateCmd.PersistentFlags().String("namespace", "", "Namespace of the secret when using the k8s-secret format")
+	generateCmd.PersistentFlags().String("service", "", "Only output the CA certificates and the certificates for this service (for example full_node). Ignored for the files format")
+	generateCmd.MarkFlagsMutuallyExclusive("ca", "ca-bundle")
 
 	cobra.CheckErr(viper.BindPFlag("ca", generateCmd.PersistentFlags().Lookup("ca")))
+	cobra.CheckErr(viper.BindPFlag("ca-bundle", generateCmd.PersistentFlags().Lookup("ca-bundle")))
 	cobra.CheckErr(viper.BindPFlag("cert-output", generateCmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("cert-format", generateCmd.PersistentFlags().Lookup("format")))
+	cobra.CheckErr(viper.BindPFlag("cert-secret-name", generateCmd.PersistentFlags().Lookup("secret-name")))
+	cobra.CheckErr(viper.BindPFlag("cert-namespace", generateCmd.PersistentFlags().Lookup("namespace")))
+	cobra.CheckErr(viper.BindPFlag("cert-service", generateCmd.PersistentFlags().Lookup("service")))
+
+	addCertOptionFlags(generateCmd)
 
 	certsCmd.AddCommand(generateCmd)
 }
@@ -1,8 +1,12 @@
 package certs
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/chik-network/go-chik-libs/pkg/tls"
 	"github.com/chik-network/go-modules/pkg/slogs"
@@ -13,15 +17,21 @@ import (
 
 // generateCACmd represents the generate CA command
 var generateCACmd = &cobra.Command{
-	Use:     "generate-ca",
-	Short:   "Generates a new random CA",
-	Example: "chik-tools certs generate-ca",
+	Use:   "generate-ca",
+	Short: "Generates a new random CA",
+	Example: `chik-tools certs generate-ca
+
+# Write the CA files to a directory that can be used with certs generate --ca
+chik-tools certs generate-ca --output ./ca
+
+# Output the CA as a kubernetes secret
+chik-tools certs generate-ca --format k8s-secret --secret-name chik-ca --namespace chik | kubectl apply -f -`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Get the public CA cert and key byte slices
 		publicCACrtBytes, publicCAKeyBytes := tls.GetChikCACertAndKey()
 
 		// Generate a private CA cert and key
-		privateCACrt, privateCAKey, err := tls.GenerateNewCA()
+		privateCACrt, privateCAKey, err := generateCA(certOptionsFromViper())
 		if err != nil {
 			slogs.Logr.Fatal("encountered error generating new private CA cert and key", "error", err)
 		}
@@ -39,11 +49,31 @@ var generateCACmd = &cobra.Command{
 			"private_ca.key": string(privateCAKeyBytes),
 		}
 
-		var marshalled []byte
+		if outputDir := viper.GetString("ca-gen-output"); outputDir != "" {
+			err = writeCABundle(outputDir, toMarshal)
+			if err != nil {
+				slogs.Logr.Fatal("error writing CA files", "error", err)
+			}
+			return
+		}
+
+		format := viper.GetString("ca-gen-format")
 		if viper.GetBool("ca-gen-as-json") {
+			format = "json"
+		}
+
+		var marshalled []byte
+		switch format {
+		case "json":
 			marshalled, err = json.Marshal(toMarshal)
-		} else {
+		case "yaml":
 			marshalled, err = yaml.Marshal(toMarshal)
+		default:
+			var files []bundleFile
+			for name, content := range toMarshal {
+				files = append(files, bundleFile{Name: name, Content: []byte(content)})
+			}
+			marshalled, err = formatFiles(format, files, viper.GetString("ca-gen-secret-name"), viper.GetString("ca-gen-namespace"))
 		}
 
 		if err != nil {
@@ -53,9 +83,82 @@ var generateCACmd = &cobra.Command{
 	},
 }
 
+// writeCABundle writes each of the CA files in the bundle to the output directory
+// Keys are only readable by the owner, matching the permissions chik uses
+func writeCABundle(outputDir string, bundle map[string]string) error {
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	for name, content := range bundle {
+		perm := os.FileMode(0644)
+		if filepath.Ext(name) == ".key" {
+			perm = 0600
+		}
+		filePath := filepath.Join(outputDir, name)
+		err = os.WriteFile(filePath, []byte(content), perm)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+		// WriteFile doesn't change the permissions of existing files
+		err = os.Chmod(filePath, perm)
+		if err != nil {
+			return fmt.Errorf("error setting permissions on %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// loadCABundle reads the private CA from a file in the format output by generate-ca
+func loadCABundle(bundlePath string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	content, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading CA bundle: %w", err)
+	}
+
+	// JSON is valid yaml, so this handles both output formats
+	bundle := map[string]string{}
+	err = yaml.Unmarshal(content, &bundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing CA bundle: %w", err)
+	}
+
+	certPEM, ok := bundle["private_ca.crt"]
+	if !ok {
+		return nil, nil, fmt.Errorf("private_ca.crt is missing from the CA bundle")
+	}
+	keyPEM, ok := bundle["private_ca.key"]
+	if !ok {
+		return nil, nil, fmt.Errorf("private_ca.key is missing from the CA bundle")
+	}
+
+	privateCACert, err := tls.ParsePemCertificate([]byte(certPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing private_ca.crt from the CA bundle: %w", err)
+	}
+	privateCAKey, err := tls.ParsePemKey([]byte(keyPEM))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing private_ca.key from the CA bundle: %w", err)
+	}
+
+	return privateCACert, privateCAKey, nil
+}
+
 func init() {
 	generateCACmd.PersistentFlags().Bool("as-json", false, "Output as JSON blob instead of yaml")
+	generateCACmd.PersistentFlags().StringP("output", "o", "", "Write the CA certificates and keys to this directory instead of printing them")
+	generateCACmd.PersistentFlags().String("format", "yaml", "Output format, one of yaml, json, k8s-secret, env")
+	generateCACmd.PersistentFlags().String("secret-name", "chik-ca", "Name of the secret when using the k8s-secret format")
+	generateCACmd.PersistentFlags().String("namespace", "", "Namespace of the secret when using the k8s-secret format")
 	cobra.CheckErr(viper.BindPFlag("ca-gen-as-json", generateCACmd.PersistentFlags().Lookup("as-json")))
+	cobra.CheckErr(viper.BindPFlag("ca-gen-output", generateCACmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("ca-gen-format", generateCACmd.PersistentFlags().Lookup("format")))
+	cobra.CheckErr(viper.BindPFlag("ca-gen-secret-name", generateCACmd.PersistentFlags().Lookup("secret-name")))
+	cobra.CheckErr(viper.BindPFlag("ca-gen-namespace", generateCACmd.PersistentFlags().Lookup("namespace")))
+
+	addCertOptionFlags(generateCACmd)
 
 	certsCmd.AddCommand(generateCACmd)
 }
@@ -0,0 +1,141 @@
+package certs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// certificateInfo is the information shown about a single certificate
+type certificateInfo struct {
+	Path        string    `json:"path"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Serial      string    `json:"serial"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"sha256_fingerprint"`
+	NodeID      string    `json:"node_id"`
+}
+
+// inspectCmd shows information about one or more certificates
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <file.crt|ssl-dir>...",
+	Short: "Shows the subject, validity, and node ID of certificates",
+	Example: `chik-tools certs inspect ~/.chik/mainnet/config/ssl/full_node/public_full_node.crt
+
+# Inspect every certificate in an ssl directory and output as JSON
+chik-tools certs inspect ~/.chik/mainnet/config/ssl --as-json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		certPaths, err := collectCertificatePaths(args)
+		if err != nil {
+			slogs.Logr.Fatal("error finding certificates", "error", err)
+		}
+
+		var infos []certificateInfo
+		for _, certPath := range certPaths {
+			info, err := inspectCertificate(certPath)
+			if err != nil {
+				slogs.Logr.Fatal("error inspecting certificate", "path", certPath, "error", err)
+			}
+			infos = append(infos, info)
+		}
+
+		if viper.GetBool("inspect-as-json") {
+			jsonOutput, err := json.MarshalIndent(infos, "", "  ")
+			if err != nil {
+				slogs.Logr.Fatal("error marshaling certificate info to JSON", "error", err)
+			}
+			fmt.Println(string(jsonOutput))
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		for idx, info := range infos {
+			if idx > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			_, _ = fmt.Fprintln(w, "Path\t", info.Path)
+			_, _ = fmt.Fprintln(w, "Subject\t", info.Subject)
+			_, _ = fmt.Fprintln(w, "Issuer\t", info.Issuer)
+			_, _ = fmt.Fprintln(w, "Serial\t", info.Serial)
+			_, _ = fmt.Fprintln(w, "Not Before\t", info.NotBefore.Format(time.RFC3339))
+			_, _ = fmt.Fprintln(w, "Not After\t", info.NotAfter.Format(time.RFC3339))
+			_, _ = fmt.Fprintln(w, "SHA-256 Fingerprint\t", info.Fingerprint)
+			_, _ = fmt.Fprintln(w, "Node ID\t", info.NodeID)
+		}
+		_ = w.Flush()
+	},
+}
+
+// inspectCertificate loads the certificate at the path and returns the details about it
+func inspectCertificate(certPath string) (certificateInfo, error) {
+	cert, err := loadCertificate(certPath)
+	if err != nil {
+		return certificateInfo{}, err
+	}
+
+	// The node ID is the sha256 hash of the DER encoded certificate, which is the same value as the fingerprint
+	hash := sha256.Sum256(cert.Raw)
+	fingerprint := make([]string, len(hash))
+	for i, b := range hash {
+		fingerprint[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return certificateInfo{
+		Path:        certPath,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		Serial:      cert.SerialNumber.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: strings.Join(fingerprint, ":"),
+		NodeID:      hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// collectCertificatePaths expands any directories in the list of paths to all .crt files within them
+func collectCertificatePaths(paths []string) ([]string, error) {
+	var certPaths []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			certPaths = append(certPaths, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(walkPath string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(walkPath) == ".crt" {
+				certPaths = append(certPaths, walkPath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return certPaths, nil
+}
+
+func init() {
+	inspectCmd.PersistentFlags().Bool("as-json", false, "Output as JSON instead of a table")
+	cobra.CheckErr(viper.BindPFlag("inspect-as-json", inspectCmd.PersistentFlags().Lookup("as-json")))
+
+	certsCmd.AddCommand(inspectCmd)
+}
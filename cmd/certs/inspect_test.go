@@ -0,0 +1,73 @@
+package certs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate is a fixed certificate, so the node ID can be pinned to the fingerprint openssl reports for it
+// openssl x509 -noout -fingerprint -sha256 gives 7A:5D:3B:92:...:52:2E
+const testCertificate = `-----BEGIN CERTIFICATE-----
+MIIBgjCCASegAwIBAgICBNIwCgYIKoZIzj0EAwIwHjENMAsGA1UEAwwEQ2hpazEN
+MAsGA1UECgwEQ2hpazAgFw0yNjEwMTkwMTAzMjZaGA8yMTI2MDkyNTAxMDMyNlow
+HjENMAsGA1UEAwwEQ2hpazENMAsGA1UECgwEQ2hpazBZMBMGByqGSM49AgEGCCqG
+SM49AwEHA0IABMjJ13wJDP2LgtWvH5spo5K8cEoMzDixmr4R6KT10HAF21aG3YMY
+5xP8rY+oHsh6oRHIFq9wCV2CtXPO6JqNerWjUzBRMB0GA1UdDgQWBBQ630B8Fx3z
+2ULoEaQvGUFfVuL4ATAfBgNVHSMEGDAWgBQ630B8Fx3z2ULoEaQvGUFfVuL4ATAP
+BgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQD3bwLC56YTwrHuIwP7
+RcuXDmQQLM3VfKdVnxYFuclRLQIhAITsfQpFttyBZ66weOP++bbna5L+lJsFUTs4
+pgHFRHKv
+-----END CERTIFICATE-----
+`
+
+func TestInspectCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "node.crt")
+	assert.NoError(t, os.WriteFile(certPath, []byte(testCertificate), 0644))
+
+	info, err := inspectCertificate(certPath)
+	assert.NoError(t, err)
+	assert.Equal(t, certificateInfo{
+		Path:        certPath,
+		Subject:     "CN=Chik,O=Chik",
+		Issuer:      "CN=Chik,O=Chik",
+		Serial:      "1234",
+		NotBefore:   time.Date(2026, time.October, 19, 1, 3, 26, 0, time.UTC),
+		NotAfter:    time.Date(2126, time.September, 25, 1, 3, 26, 0, time.UTC),
+		Fingerprint: "7A:5D:3B:92:03:98:67:10:38:4F:81:F4:B1:16:4C:42:A6:17:59:35:8B:FC:72:FF:E1:75:B9:8A:D5:66:52:2E",
+		NodeID:      "7a5d3b9203986710384f81f4b1164c42a61759358bfc72ffe175b98ad566522e",
+	}, info)
+
+	notPEM := filepath.Join(dir, "node.key")
+	assert.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0644))
+	_, err = inspectCertificate(notPEM)
+	assert.Error(t, err)
+
+	_, err = inspectCertificate(filepath.Join(dir, "missing.crt"))
+	assert.Error(t, err)
+}
+
+func TestCollectCertificatePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"ca/chik_ca.crt", "ca/chik_ca.key", "full_node/private_full_node.crt", "full_node/private_full_node.key", "README"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(testCertificate), 0644))
+	}
+
+	// Files given directly are kept whatever their extension, directories only contribute .crt files
+	key := filepath.Join(dir, "ca", "chik_ca.key")
+	paths, err := collectCertificatePaths([]string{key, dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		key,
+		filepath.Join(dir, "ca", "chik_ca.crt"),
+		filepath.Join(dir, "full_node", "private_full_node.crt"),
+	}, paths)
+
+	_, err = collectCertificatePaths([]string{filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
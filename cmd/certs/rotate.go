@@ -0,0 +1,300 @@
+package certs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/rpcinterface"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// daemonServiceOptions is the request for the daemon start_service and stop_service commands
+type daemonServiceOptions struct {
+	Service string `json:"service"`
+}
+
+// daemonServiceResponse is the response from the daemon start_service and stop_service commands
+type daemonServiceResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+// daemonRunningServicesResponse is the response from the daemon running_services command
+type daemonRunningServicesResponse struct {
+	Success         bool     `json:"success"`
+	RunningServices []string `json:"running_services"`
+}
+
+// rotateCmd regenerates the private node certificates in CHIK_ROOT
+var rotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Regenerates the private node certificates in CHIK_ROOT from the existing private CA",
+	Example: `chik-tools certs rotate
+
+# Stop any running services before rotating, and start them again afterward
+chik-tools certs rotate --restart
+
+# Show which files would change without actually changing them
+chik-tools certs rotate --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		chikRoot, err := config.GetChikRootPath()
+		if err != nil {
+			slogs.Logr.Fatal("Unable to determine CHIK_ROOT", "error", err)
+		}
+
+		err = rotatePrivateCerts(path.Join(chikRoot, "config", "ssl"))
+		if err != nil {
+			slogs.Logr.Fatal("error rotating certificates", "error", err)
+		}
+	},
+}
+
+// rotatePrivateCerts generates new private node certificates and replaces the ones in sslDir
+// Errors are returned instead of exiting, so the generated keys in the temp directory are always removed
+func rotatePrivateCerts(sslDir string) error {
+	privateCACert, privateCAKey, err := loadPrivateCA(path.Join(sslDir, "ca"))
+	if err != nil {
+		return fmt.Errorf("error loading private CA: %w", err)
+	}
+
+	// Generate a full set into a temp directory, and only take the private node certs from it
+	tmpDir, err := os.MkdirTemp("", "chik-tools-rotate-*")
+	if err != nil {
+		return fmt.Errorf("error creating temp directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	err = generateAllCerts(tmpDir, privateCACert, privateCAKey, certOptionsFromViper())
+	if err != nil {
+		return fmt.Errorf("error generating certificates: %w", err)
+	}
+
+	files, err := privateNodeFiles(tmpDir)
+	if err != nil {
+		return fmt.Errorf("error finding generated certificates: %w", err)
+	}
+
+	if viper.GetBool("dry-run") {
+		slogs.Logr.Info("DRY RUN: The following files would be replaced", "ssl_dir", sslDir)
+		for _, file := range files {
+			action := "replace"
+			if _, err := os.Stat(filepath.Join(sslDir, file)); errors.Is(err, os.ErrNotExist) {
+				action = "create"
+			}
+			slogs.Logr.Info("DRY RUN: Would update certificate file", "file", file, "action", action)
+		}
+		if viper.GetBool("rotate-restart") {
+			slogs.Logr.Info("DRY RUN: Would stop and restart any running services")
+		}
+		slogs.Logr.Info("DRY RUN: No changes were made to the certificates")
+		return nil
+	}
+
+	if !utils.ConfirmAction(fmt.Sprintf("Replace %d private certificate files in %s? (y/N)", len(files), sslDir), viper.GetBool("rotate-yes")) {
+		slogs.Logr.Error("Cancelled")
+		return nil
+	}
+
+	backupDir := fmt.Sprintf("%s-backup-%s", sslDir, time.Now().Format("20060102-150405"))
+	slogs.Logr.Info("backing up existing certificates", "backup", backupDir)
+	err = utils.CopyDir(sslDir, backupDir)
+	if err != nil {
+		return fmt.Errorf("error backing up existing certificates: %w", err)
+	}
+
+	var daemon daemonServices
+	if viper.GetBool("rotate-restart") {
+		client, err := rpc.NewClient(rpc.ConnectionModeWebsocket, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
+		if err != nil {
+			return fmt.Errorf("error initializing websocket RPC client: %w", err)
+		}
+		daemon = rpcDaemonServices{client: client}
+	}
+
+	err = replacePrivateCerts(daemon, tmpDir, sslDir, backupDir, files)
+	if err != nil {
+		return err
+	}
+	if daemon == nil {
+		slogs.Logr.Info("Rotated certificates. Restart your chik services for the new certificates to take effect")
+	}
+	return nil
+}
+
+// replacePrivateCerts copies the files from srcDir into sslDir, stopping the running services first when daemon is set
+// Every service that was stopped is started again on the way out, and a failed copy restores the files from the backup
+func replacePrivateCerts(daemon daemonServices, srcDir, sslDir, backupDir string, files []string) error {
+	if daemon != nil {
+		stopped, err := stopRunningServices(daemon)
+		defer startServices(daemon, stopped)
+		if err != nil {
+			return fmt.Errorf("error stopping running services: %w", err)
+		}
+	}
+
+	for _, file := range files {
+		slogs.Logr.Info("updating certificate file", "file", file)
+		err := utils.CopyFile(filepath.Join(srcDir, file), filepath.Join(sslDir, file))
+		if err != nil {
+			restoreErr := restorePrivateCerts(backupDir, sslDir, files)
+			if restoreErr != nil {
+				return fmt.Errorf("error writing %s: %w. Restoring the previous certificates also failed, restore them manually from %s: %v", file, err, backupDir, restoreErr)
+			}
+			return fmt.Errorf("error writing %s. The previous certificates were restored from %s: %w", file, backupDir, err)
+		}
+	}
+	return nil
+}
+
+// restorePrivateCerts puts the files back as they were in the backup, removing any that didn't exist before
+func restorePrivateCerts(backupDir, sslDir string, files []string) error {
+	var errs []error
+	for _, file := range files {
+		backup := filepath.Join(backupDir, file)
+		if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+			err = os.Remove(filepath.Join(sslDir, file))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := utils.CopyFile(backup, filepath.Join(sslDir, file)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// privateNodeFiles returns the paths, relative to sslDir, of all private node certificates and keys
+func privateNodeFiles(sslDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(sslDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sslDir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Dir(relPath) == "ca" || !strings.HasPrefix(d.Name(), "private_") {
+			return nil
+		}
+		files = append(files, relPath)
+		return nil
+	})
+	return files, err
+}
+
+// daemonServices lists, starts and stops services through the daemon
+type daemonServices interface {
+	runningServices() ([]string, error)
+	startService(service string) error
+	stopService(service string) error
+}
+
+// rpcDaemonServices calls the daemon over the websocket RPC
+type rpcDaemonServices struct {
+	client *rpc.Client
+}
+
+func (d rpcDaemonServices) runningServices() ([]string, error) {
+	req, err := d.client.DaemonService.NewRequest("running_services", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	running := &daemonRunningServicesResponse{}
+	_, err = d.client.DaemonService.Do(req, running)
+	if err != nil {
+		return nil, err
+	}
+	if !running.Success {
+		return nil, fmt.Errorf("daemon was unable to list running services")
+	}
+	return running.RunningServices, nil
+}
+
+func (d rpcDaemonServices) startService(service string) error {
+	return callDaemonService(d.client, "start_service", service)
+}
+
+func (d rpcDaemonServices) stopService(service string) error {
+	return callDaemonService(d.client, "stop_service", service)
+}
+
+// stopRunningServices stops every service the daemon reports as running, and returns the list of stopped services
+// When stopping a service fails, the services stopped so far are still returned so they can be started again
+func stopRunningServices(daemon daemonServices) ([]string, error) {
+	running, err := daemon.runningServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped []string
+	for _, service := range running {
+		// The daemon lists itself in some versions, and stopping it would end our connection
+		if service == "daemon" {
+			continue
+		}
+		slogs.Logr.Info("stopping service", "service", service)
+		err = daemon.stopService(service)
+		if err != nil {
+			return stopped, fmt.Errorf("error stopping %s: %w", service, err)
+		}
+		stopped = append(stopped, service)
+	}
+
+	return stopped, nil
+}
+
+// startServices starts each of the services again, logging any that fail so the rest are still started
+func startServices(daemon daemonServices, services []string) {
+	for _, service := range services {
+		slogs.Logr.Info("starting service", "service", service)
+		err := daemon.startService(service)
+		if err != nil {
+			slogs.Logr.Error("error starting service", "service", service, "error", err)
+		}
+	}
+}
+
+// callDaemonService calls start_service or stop_service on the daemon for a single service
+func callDaemonService(client *rpc.Client, command rpcinterface.Endpoint, service string) error {
+	req, err := client.DaemonService.NewRequest(command, &daemonServiceOptions{Service: service})
+	if err != nil {
+		return err
+	}
+	resp := &daemonServiceResponse{}
+	_, err = client.DaemonService.Do(req, resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("daemon returned an error: %s", resp.Error)
+	}
+	return nil
+}
+
+func init() {
+	rotateCmd.PersistentFlags().Bool("restart", false, "Stop any running services through the daemon before rotating and start them again afterward")
+	rotateCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+
+	cobra.CheckErr(viper.BindPFlag("rotate-restart", rotateCmd.PersistentFlags().Lookup("restart")))
+	cobra.CheckErr(viper.BindPFlag("rotate-yes", rotateCmd.PersistentFlags().Lookup("yes")))
+
+	addCertOptionFlags(rotateCmd)
+
+	certsCmd.AddCommand(rotateCmd)
+}
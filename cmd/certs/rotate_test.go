@@ -0,0 +1,114 @@
+package certs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+// fakeDaemon records the services started and stopped through it
+type fakeDaemon struct {
+	running  []string
+	failStop string
+	calls    []string
+}
+
+func (d *fakeDaemon) runningServices() ([]string, error) {
+	return d.running, nil
+}
+
+func (d *fakeDaemon) startService(service string) error {
+	d.calls = append(d.calls, "start "+service)
+	return nil
+}
+
+func (d *fakeDaemon) stopService(service string) error {
+	if service == d.failStop {
+		return errors.New("timed out")
+	}
+	d.calls = append(d.calls, "stop "+service)
+	return nil
+}
+
+// writeFiles writes each file, relative to dir, with the given content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for file, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0600))
+	}
+}
+
+func TestPrivateNodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"ca/private_ca.crt":                "",
+		"ca/chik_ca.crt":                   "",
+		"full_node/private_full_node.crt":  "",
+		"full_node/private_full_node.key":  "",
+		"full_node/public_full_node.crt":   "",
+		"daemon/private_daemon.key":        "",
+		"introducer/public_introducer.key": "",
+	})
+
+	files, err := privateNodeFiles(dir)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join("daemon", "private_daemon.key"),
+		filepath.Join("full_node", "private_full_node.crt"),
+		filepath.Join("full_node", "private_full_node.key"),
+	}, files)
+}
+
+func TestReplacePrivateCerts(t *testing.T) {
+	cmd.InitLogs()
+	files := []string{filepath.Join("full_node", "private_full_node.crt"), filepath.Join("wallet", "private_wallet.crt")}
+	setup := func() (string, string, string) {
+		src, ssl, backup := t.TempDir(), t.TempDir(), t.TempDir()
+		writeFiles(t, src, map[string]string{files[0]: "new node", files[1]: "new wallet"})
+		writeFiles(t, ssl, map[string]string{files[0]: "old node"})
+		writeFiles(t, backup, map[string]string{files[0]: "old node"})
+		return src, ssl, backup
+	}
+	read := func(dir, file string) string {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return ""
+		}
+		return string(content)
+	}
+
+	// The running services are stopped before the copy and started again after it
+	src, ssl, backup := setup()
+	daemon := &fakeDaemon{running: []string{"daemon", "chik_full_node", "chik_wallet"}}
+	assert.NoError(t, replacePrivateCerts(daemon, src, ssl, backup, files))
+	assert.Equal(t, []string{"stop chik_full_node", "stop chik_wallet", "start chik_full_node", "start chik_wallet"}, daemon.calls)
+	assert.Equal(t, "new node", read(ssl, files[0]))
+	assert.Equal(t, "new wallet", read(ssl, files[1]))
+
+	// Services stopped before a stop fails are started again, and nothing is copied
+	src, ssl, backup = setup()
+	daemon = &fakeDaemon{running: []string{"chik_full_node", "chik_wallet"}, failStop: "chik_wallet"}
+	assert.Error(t, replacePrivateCerts(daemon, src, ssl, backup, files))
+	assert.Equal(t, []string{"stop chik_full_node", "start chik_full_node"}, daemon.calls)
+	assert.Equal(t, "old node", read(ssl, files[0]))
+
+	// A failed copy restores the backup, removes files that didn't exist before and still starts the services
+	src, ssl, backup = setup()
+	assert.NoError(t, os.Remove(filepath.Join(src, files[1])))
+	daemon = &fakeDaemon{running: []string{"chik_full_node"}}
+	err := replacePrivateCerts(daemon, src, ssl, backup, files)
+	assert.ErrorContains(t, err, "restored")
+	assert.Equal(t, []string{"stop chik_full_node", "start chik_full_node"}, daemon.calls)
+	assert.Equal(t, "old node", read(ssl, files[0]))
+	assert.NoFileExists(t, filepath.Join(ssl, files[1]))
+
+	// Without the daemon the files are only copied
+	src, ssl, backup = setup()
+	assert.NoError(t, replacePrivateCerts(nil, src, ssl, backup, files))
+	assert.Equal(t, "new node", read(ssl, files[0]))
+}
@@ -0,0 +1,200 @@
+package certs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// unbundleCmd installs a bundle created by the bundle command into CHIK_ROOT
+var unbundleCmd = &cobra.Command{
+	Use:   "unbundle <bundle.tar.gz>",
+	Short: "Installs a certificate bundle created with certs bundle into CHIK_ROOT",
+	Example: `chik-tools certs unbundle harvester.tar.gz
+
+# Show what changes would be made without actually making them
+chik-tools certs unbundle harvester.tar.gz --dry-run`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		chikRoot, err := config.GetChikRootPath()
+		if err != nil {
+			slogs.Logr.Fatal("Unable to determine CHIK_ROOT", "error", err)
+		}
+		sslDir := path.Join(chikRoot, "config", "ssl")
+
+		files, err := readTarGz(args[0])
+		if err != nil {
+			slogs.Logr.Fatal("error reading bundle", "error", err)
+		}
+
+		var snippet *harvesterConfigSnippet
+		var sslFiles []bundleFile
+		for _, file := range files {
+			if file.Name == bundleConfigSnippet {
+				snippet = &harvesterConfigSnippet{}
+				err = yaml.Unmarshal(file.Content, snippet)
+				if err != nil {
+					slogs.Logr.Fatal("error parsing config snippet from bundle", "error", err)
+				}
+				continue
+			}
+			if !strings.HasPrefix(file.Name, "ssl/") {
+				slogs.Logr.Warn("skipping unexpected file in bundle", "file", file.Name)
+				continue
+			}
+			sslFiles = append(sslFiles, file)
+		}
+
+		dryRun := viper.GetBool("dry-run")
+		if dryRun {
+			for _, file := range sslFiles {
+				slogs.Logr.Info("DRY RUN: Would write certificate file", "file", filepath.Join(chikRoot, "config", filepath.FromSlash(file.Name)))
+			}
+			if snippet != nil {
+				slogs.Logr.Info("DRY RUN: Would set harvester farmer peers", "farmer_peers", snippet.Harvester.FarmerPeers)
+			}
+			slogs.Logr.Info("DRY RUN: No changes were made")
+			return
+		}
+
+		if !utils.ConfirmAction(fmt.Sprintf("Install %d certificate files into %s? (y/N)", len(sslFiles), sslDir), viper.GetBool("unbundle-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		if _, err := os.Stat(sslDir); err == nil {
+			backupDir := fmt.Sprintf("%s-backup-%s", sslDir, time.Now().Format("20060102-150405"))
+			slogs.Logr.Info("backing up existing certificates", "backup", backupDir)
+			err = utils.CopyDir(sslDir, backupDir)
+			if err != nil {
+				slogs.Logr.Fatal("error backing up existing certificates", "error", err)
+			}
+		}
+
+		err = installSSLFiles(filepath.Join(chikRoot, "config"), sslFiles)
+		if err != nil {
+			slogs.Logr.Fatal("error installing certificates", "error", err)
+		}
+
+		if snippet != nil {
+			cfg, err := config.GetChikConfig()
+			if err != nil {
+				slogs.Logr.Fatal("error loading config", "error", err)
+			}
+			err = cfg.SetFieldByPath([]string{"harvester", "farmer_peers"}, snippet.Harvester.FarmerPeers)
+			if err != nil {
+				slogs.Logr.Fatal("error setting harvester farmer peers", "error", err)
+			}
+			err = cfg.Save()
+			if err != nil {
+				slogs.Logr.Fatal("error saving config", "error", err)
+			}
+		}
+
+		slogs.Logr.Info("Installed bundle. Restart your chik services for the configuration to take effect")
+	},
+}
+
+// installSSLFiles writes the ssl files from a bundle into configDir with the permissions from the bundle
+func installSSLFiles(configDir string, sslFiles []bundleFile) error {
+	for _, file := range sslFiles {
+		target := filepath.Join(configDir, filepath.FromSlash(file.Name))
+		slogs.Logr.Info("writing certificate file", "file", target)
+		err := os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return fmt.Errorf("error creating certificate directory: %w", err)
+		}
+		err = os.WriteFile(target, file.Content, os.FileMode(file.Mode).Perm())
+		if err != nil {
+			return fmt.Errorf("error writing certificate file %s: %w", target, err)
+		}
+		err = os.Chmod(target, os.FileMode(file.Mode).Perm())
+		if err != nil {
+			return fmt.Errorf("error setting permissions on certificate file %s: %w", target, err)
+		}
+	}
+
+	// Bundles carry the farmer's private CA certificate but not its key. The existing key belongs to the old CA
+	// and no longer matches, so it is removed. The ssl directory is backed up before installing
+	shipped := map[string]bool{}
+	for _, file := range sslFiles {
+		shipped[file.Name] = true
+	}
+	if shipped["ssl/ca/private_ca.crt"] && !shipped["ssl/ca/private_ca.key"] {
+		staleKey := filepath.Join(configDir, "ssl", "ca", "private_ca.key")
+		err := os.Remove(staleKey)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error removing private CA key that doesn't match the new private CA: %w", err)
+		}
+		if err == nil {
+			slogs.Logr.Info("removed private CA key that doesn't match the new private CA", "file", staleKey)
+		}
+	}
+	return nil
+}
+
+// readTarGz reads all regular files from a gzipped tarball
+// File names that could escape the destination directory are rejected
+func readTarGz(bundlePath string) ([]bundleFile, error) {
+	in, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("error opening gzip stream: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var files []bundleFile
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid file name in bundle: %s", header.Name)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from bundle: %w", name, err)
+		}
+		files = append(files, bundleFile{Name: name, Mode: header.Mode, Content: content})
+	}
+
+	return files, nil
+}
+
+func init() {
+	unbundleCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+	cobra.CheckErr(viper.BindPFlag("unbundle-yes", unbundleCmd.PersistentFlags().Lookup("yes")))
+
+	certsCmd.AddCommand(unbundleCmd)
+}
@@ -0,0 +1,302 @@
+package certs
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-chik-libs/pkg/tls"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// verifyResult is a single check performed by the verify command
+type verifyResult struct {
+	Check  string
+	Path   string
+	Passed bool
+	Detail string
+}
+
+// sslConfigKeys are the keys in config.yaml that reference a certificate or key on disk
+var sslConfigKeys = []string{"crt", "key", "private_crt", "private_key", "public_crt", "public_key"}
+
+// verifyCmd verifies a directory of chik certificates
+var verifyCmd = &cobra.Command{
+	Use:   "verify <ssl-dir>",
+	Short: "Verifies the certificates, keys, and permissions in a chik ssl directory",
+	Example: `chik-tools certs verify ~/.chik/mainnet/config/ssl
+
+# Verify the certificates against a specific config file
+chik-tools certs verify ./ssl --config ./config.yaml`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		results := verifySSLDir(args[0])
+
+		if !viper.GetBool("verify-skip-config") {
+			configResults, err := verifyConfigPaths(viper.GetString("verify-config"))
+			if err != nil {
+				slogs.Logr.Fatal("error checking paths from config", "error", err)
+			}
+			results = append(results, configResults...)
+		}
+
+		if !printVerifyResults(os.Stdout, results) {
+			os.Exit(1)
+		}
+	},
+}
+
+// verifySSLDir checks every certificate and key in the ssl directory against the CA that should have signed it
+func verifySSLDir(sslDir string) []verifyResult {
+	var results []verifyResult
+
+	cas := map[string]*x509.Certificate{}
+	for prefix, caName := range map[string]string{"private_": "private_ca", "public_": "chik_ca"} {
+		caResults, caCert := verifyPair(sslDir, path.Join("ca", caName), nil)
+		results = append(results, caResults...)
+		if caCert != nil {
+			cas[prefix] = caCert
+		}
+	}
+
+	var certPaths []string
+	err := filepath.WalkDir(sslDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".crt" {
+			return nil
+		}
+		relPath, err := filepath.Rel(sslDir, p)
+		if err != nil {
+			return err
+		}
+		if filepath.Dir(relPath) == "ca" {
+			return nil
+		}
+		certPaths = append(certPaths, relPath)
+		return nil
+	})
+	if err != nil {
+		results = append(results, verifyResult{Check: "read directory", Path: sslDir, Detail: err.Error()})
+		return results
+	}
+	sort.Strings(certPaths)
+
+	for _, certPath := range certPaths {
+		base := strings.TrimSuffix(filepath.ToSlash(certPath), ".crt")
+		var signer *x509.Certificate
+		for prefix, caCert := range cas {
+			if strings.HasPrefix(path.Base(base), prefix) {
+				signer = caCert
+			}
+		}
+		if signer == nil {
+			slogs.Logr.Debug("no ca found for certificate, skipping signature check", "path", certPath)
+		}
+		pairResults, _ := verifyPair(sslDir, base, signer)
+		results = append(results, pairResults...)
+	}
+
+	return results
+}
+
+// verifyPair checks the certificate and key at base.crt and base.key
+// If signer is not nil, the certificate must be signed by it
+func verifyPair(sslDir, base string, signer *x509.Certificate) ([]verifyResult, *x509.Certificate) {
+	var results []verifyResult
+	certPath := base + ".crt"
+	keyPath := base + ".key"
+
+	cert, err := loadCertificate(filepath.Join(sslDir, certPath))
+	if err != nil {
+		return append(results, verifyResult{Check: "load certificate", Path: certPath, Detail: err.Error()}), nil
+	}
+
+	validity := verifyResult{Check: "validity", Path: certPath, Passed: true, Detail: fmt.Sprintf("expires %s", cert.NotAfter.Format(time.DateOnly))}
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		validity.Passed = false
+		validity.Detail = fmt.Sprintf("not valid until %s", cert.NotBefore.Format(time.DateOnly))
+	} else if now.After(cert.NotAfter) {
+		validity.Passed = false
+		validity.Detail = fmt.Sprintf("expired %s", cert.NotAfter.Format(time.DateOnly))
+	}
+	results = append(results, validity)
+
+	if signer != nil {
+		signature := verifyResult{Check: "signed by ca", Path: certPath, Passed: true, Detail: signer.Subject.CommonName}
+		if err := cert.CheckSignatureFrom(signer); err != nil {
+			signature.Passed = false
+			signature.Detail = err.Error()
+		}
+		results = append(results, signature)
+	}
+
+	key, err := loadKey(filepath.Join(sslDir, keyPath))
+	if err != nil {
+		return append(results, verifyResult{Check: "load key", Path: keyPath, Detail: err.Error()}), cert
+	}
+
+	match := verifyResult{Check: "key matches", Path: keyPath, Passed: true}
+	if pub, ok := cert.PublicKey.(*rsa.PublicKey); !ok || !pub.Equal(&key.PublicKey) {
+		match.Passed = false
+		match.Detail = "key does not match certificate"
+	}
+	results = append(results, match)
+
+	// Windows doesn't have unix style permissions, so there is nothing to check
+	if runtime.GOOS != "windows" {
+		perms := verifyResult{Check: "permissions", Path: keyPath, Passed: true}
+		info, err := os.Stat(filepath.Join(sslDir, keyPath))
+		if err != nil {
+			perms.Passed = false
+			perms.Detail = err.Error()
+		} else {
+			perms.Detail = fmt.Sprintf("%#o", info.Mode().Perm())
+			if info.Mode().Perm() != 0600 {
+				perms.Passed = false
+				perms.Detail = fmt.Sprintf("%#o, expected 0600", info.Mode().Perm())
+			}
+		}
+		results = append(results, perms)
+	}
+
+	return results, cert
+}
+
+// verifyConfigPaths checks that every certificate and key referenced in config.yaml exists
+func verifyConfigPaths(cfgPath string) ([]verifyResult, error) {
+	chikRoot, paths, err := configSSLPaths(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var results []verifyResult
+	for _, key := range keys {
+		referenced := paths[key]
+		if !filepath.IsAbs(referenced) {
+			referenced = filepath.Join(chikRoot, referenced)
+		}
+		result := verifyResult{Check: "config path exists", Path: key, Passed: true, Detail: paths[key]}
+		if _, err := os.Stat(referenced); err != nil {
+			result.Passed = false
+			result.Detail = fmt.Sprintf("%s: %s", paths[key], err.Error())
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// configSSLPaths returns CHIK_ROOT and every certificate and key path referenced in config.yaml, keyed by config path
+// Paths are returned as they appear in the config, which is usually relative to CHIK_ROOT
+func configSSLPaths(cfgPath string) (string, map[string]string, error) {
+	chikRoot, err := config.GetChikRootPath()
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to determine CHIK_ROOT: %w", err)
+	}
+	if cfgPath == "" {
+		cfgPath = path.Join(chikRoot, "config", "config.yaml")
+	}
+
+	cfgBytes, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	var cfg map[string]any
+	err = yaml.Unmarshal(cfgBytes, &cfg)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	paths := map[string]string{}
+	collectSSLPaths(cfg, "", paths)
+
+	return chikRoot, paths, nil
+}
+
+// collectSSLPaths recursively finds all cert and key paths in the parsed config
+func collectSSLPaths(node map[string]any, prefix string, paths map[string]string) {
+	for key, value := range node {
+		fullKey := key
+		if prefix != "" {
+			fullKey = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			collectSSLPaths(v, fullKey, paths)
+		case string:
+			for _, sslKey := range sslConfigKeys {
+				if key == sslKey && prefix != "" {
+					paths[fullKey] = v
+				}
+			}
+		}
+	}
+}
+
+// printVerifyResults prints the results as a table and returns whether all checks passed
+func printVerifyResults(out io.Writer, results []verifyResult) bool {
+	allPassed := true
+	w := tabwriter.NewWriter(out, 1, 1, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RESULT\tCHECK\tPATH\tDETAIL")
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+			allPassed = false
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status, result.Check, result.Path, result.Detail)
+	}
+	_ = w.Flush()
+
+	return allPassed
+}
+
+// loadCertificate reads and parses a PEM certificate from disk
+func loadCertificate(certPath string) (*x509.Certificate, error) {
+	certBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	return tls.ParsePemCertificate(certBytes)
+}
+
+// loadKey reads and parses a PEM private key from disk
+func loadKey(keyPath string) (*rsa.PrivateKey, error) {
+	keyBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	return tls.ParsePemKey(keyBytes)
+}
+
+func init() {
+	verifyCmd.PersistentFlags().String("config", "", "existing config file to check paths in (default is to look in $CHIK_ROOT)")
+	verifyCmd.PersistentFlags().Bool("skip-config", false, "Skip checking the certificate paths referenced in config.yaml")
+
+	cobra.CheckErr(viper.BindPFlag("verify-config", verifyCmd.PersistentFlags().Lookup("config")))
+	cobra.CheckErr(viper.BindPFlag("verify-skip-config", verifyCmd.PersistentFlags().Lookup("skip-config")))
+
+	certsCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,74 @@
+package certs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/tls"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func failedChecks(results []verifyResult) []verifyResult {
+	var failed []verifyResult
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+func TestVerifySSLDir(t *testing.T) {
+	cmd.InitLogs()
+	sslDir := t.TempDir()
+	err := tls.GenerateAndWriteAllCerts(sslDir, nil, nil)
+	assert.NoError(t, err)
+
+	results := verifySSLDir(sslDir)
+	assert.NotEmpty(t, results)
+	assert.Empty(t, failedChecks(results))
+}
+
+func TestVerifySSLDir_WrongCA(t *testing.T) {
+	cmd.InitLogs()
+	sslDir := t.TempDir()
+	err := tls.GenerateAndWriteAllCerts(sslDir, nil, nil)
+	assert.NoError(t, err)
+
+	// Replace the private CA with a different one, so none of the private node certs match anymore
+	otherDir := t.TempDir()
+	err = tls.GenerateAndWriteAllCerts(otherDir, nil, nil)
+	assert.NoError(t, err)
+	for _, file := range []string{"private_ca.crt", "private_ca.key"} {
+		content, err := os.ReadFile(filepath.Join(otherDir, "ca", file))
+		assert.NoError(t, err)
+		err = os.WriteFile(filepath.Join(sslDir, "ca", file), content, 0600)
+		assert.NoError(t, err)
+	}
+
+	failed := failedChecks(verifySSLDir(sslDir))
+	assert.NotEmpty(t, failed)
+	for _, result := range failed {
+		assert.Equal(t, "signed by ca", result.Check)
+		assert.Contains(t, filepath.Base(result.Path), "private_")
+	}
+}
+
+func TestVerifySSLDir_KeyPermissions(t *testing.T) {
+	cmd.InitLogs()
+	sslDir := t.TempDir()
+	err := tls.GenerateAndWriteAllCerts(sslDir, nil, nil)
+	assert.NoError(t, err)
+
+	keyPath := filepath.Join(sslDir, "full_node", "private_full_node.key")
+	err = os.Chmod(keyPath, 0644)
+	assert.NoError(t, err)
+
+	failed := failedChecks(verifySSLDir(sslDir))
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "permissions", failed[0].Check)
+	assert.Equal(t, filepath.Join("full_node", "private_full_node.key"), filepath.FromSlash(failed[0].Path))
+}
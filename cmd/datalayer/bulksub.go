@@ -1,9 +1,14 @@
 package datalayer
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/chik-network/go-chik-libs/pkg/rpc"
 	"github.com/chik-network/go-modules/pkg/slogs"
@@ -11,12 +16,42 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Outcomes recorded in the bulk subscribe results file
+const (
+	bulkSubStatusSubscribed = "subscribed"
+	bulkSubStatusFailed     = "failed"
+)
+
+// bulkSubResult is the outcome of subscribing to a single store
+type bulkSubResult struct {
+	StoreID  string `json:"store_id"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
 // bulkSubCmd Subscribes to multiple datastores at once using the output of chik data get_subscriptions
 var bulkSubCmd = &cobra.Command{
 	Use:   "bulk-subscribe",
 	Short: "Subscribes to multiple datastores at once using the output of chik data get_subscriptions",
+	Long: `Subscribes to multiple datastores at once using the output of chik data get_subscriptions.
+
+The outcome for each store is appended to the results file as soon as it completes, so an
+interrupted run can be resumed. The results file is JSON Lines, with one object per line:
+
+  {"store_id":"<id>","status":"subscribed","attempts":1}
+  {"store_id":"<id>","status":"failed","attempts":4,"error":"<reason>"}
+
+Connection errors are retried with exponential backoff. Invalid store IDs, and subscriptions the
+data layer rejects, fail immediately without retrying.`,
 	Example: `chik-tools data bulk-subscribe -f subscriptions.json
 
+# Subscribe using 8 workers, and write the outcome for each store to results.jsonl
+chik-tools data bulk-subscribe -f subscriptions.json --concurrency 8 --results results.jsonl
+
+# Retry only the stores that failed in a previous run, or were not reached before it was interrupted
+chik-tools data bulk-subscribe -f subscriptions.json --resume results.jsonl
+
 # Show what changes would be made without actually subscribing
 chik-tools data bulk-subscribe -f subscriptions.json --dry-run`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -25,53 +60,255 @@ chik-tools data bulk-subscribe -f subscriptions.json --dry-run`,
 			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
 		}
 
-		var content []byte
-		if len(args) != 0 {
-			content = []byte(strings.Join(args, " "))
-		} else {
-			jsonFile := viper.GetString("bulksub-file")
-			content, err = os.ReadFile(jsonFile)
+		resumeFile := viper.GetString("bulksub-resume")
+		resultsFile := viper.GetString("bulksub-results")
+		if resultsFile == "" {
+			resultsFile = "bulk-subscribe-results.jsonl"
+			if resumeFile != "" {
+				resultsFile = resumeFile
+			}
+		}
+
+		var storeIDs []string
+		if len(args) != 0 || viper.GetString("bulksub-file") != "" {
+			var content []byte
+			if len(args) != 0 {
+				content = []byte(strings.Join(args, " "))
+			} else {
+				content, err = os.ReadFile(viper.GetString("bulksub-file"))
+				if err != nil {
+					slogs.Logr.Fatal("Unable to read input file", "error", err)
+				}
+			}
+
+			subs := &rpc.DatalayerGetSubscriptionsResponse{}
+			err = json.Unmarshal(content, subs)
 			if err != nil {
-				slogs.Logr.Fatal("Unable to read input file", "error", err)
+				slogs.Logr.Fatal("Could not parse the subscriptions json file", "error", err)
 			}
+			storeIDs = subs.StoreIDs
 		}
 
-		subs := &rpc.DatalayerGetSubscriptionsResponse{}
-		err = json.Unmarshal(content, subs)
-		if err != nil {
-			slogs.Logr.Fatal("Could not parse the subscriptions json file", "error", err)
+		var previous []bulkSubResult
+		if resumeFile != "" {
+			storeIDs, previous, err = loadBulkSubResume(resumeFile, storeIDs)
+			if err != nil {
+				slogs.Logr.Fatal("Unable to load results file to resume from", "error", err)
+			}
+			slogs.Logr.Info("Resuming subscriptions", "remaining", len(storeIDs), "succeeded", len(previous))
+		} else if len(storeIDs) == 0 {
+			slogs.Logr.Fatal("No stores to subscribe to. Provide a subscriptions file with -f or a results file with --resume")
 		}
 
 		dryRun := viper.GetBool("dry-run")
 		if dryRun {
 			slogs.Logr.Info("DRY RUN: Would subscribe to the following stores")
-		}
-
-		for _, id := range subs.StoreIDs {
-			if dryRun {
+			for _, id := range storeIDs {
 				slogs.Logr.Info("DRY RUN: Would subscribe to store", "id", id)
-				continue
 			}
+			slogs.Logr.Info("DRY RUN: No changes were made to subscriptions")
+			return
+		}
 
-			slogs.Logr.Info("Subscription to store", "id", id)
-			_, _, err = client.DataLayerService.Subscribe(&rpc.DatalayerSubscribeOptions{
-				ID: id,
-			})
-			if err != nil {
-				slogs.Logr.Error("Error subscribing to datastore", "id", id, "error", err)
+		concurrency := viper.GetInt("bulksub-concurrency")
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		// Results are appended as each store completes, so an interrupted run can be resumed from the file
+		// When resuming into a different file, the earlier successes are carried over so the new file is complete
+		var results *os.File
+		if resultsFile == resumeFile {
+			results, err = os.OpenFile(resultsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		} else {
+			results, err = os.Create(resultsFile)
+		}
+		if err != nil {
+			slogs.Logr.Fatal("error opening results file", "file", resultsFile, "error", err)
+		}
+		defer func(results *os.File) {
+			_ = results.Close()
+		}(results)
+
+		resultWriter := json.NewEncoder(results)
+		if resultsFile != resumeFile {
+			for _, result := range previous {
+				if err = resultWriter.Encode(result); err != nil {
+					slogs.Logr.Fatal("error writing results file", "file", resultsFile, "error", err)
+				}
 			}
 		}
 
-		if dryRun {
-			slogs.Logr.Info("DRY RUN: No changes were made to subscriptions")
+		var succeeded, failed int
+		subscribeAll(subscribeStore(client), storeIDs, concurrency, viper.GetInt("bulksub-retries"), func(result bulkSubResult) {
+			if result.Status == bulkSubStatusFailed {
+				failed++
+			} else {
+				succeeded++
+			}
+			if err := resultWriter.Encode(result); err != nil {
+				slogs.Logr.Error("error writing result to results file", "file", resultsFile, "id", result.StoreID, "error", err)
+			}
+		})
+
+		slogs.Logr.Info("Finished subscribing", "succeeded", succeeded, "failed", failed, "results", resultsFile)
+		if failed > 0 {
+			slogs.Logr.Warn("Some subscriptions failed. Retry them with --resume", "results", resultsFile)
+			_ = results.Close()
+			os.Exit(1)
 		}
 	},
 }
 
+// subscribeAll subscribes to every store using a pool of workers, calling done with the result of each store as it completes
+// done is never called concurrently
+func subscribeAll(subscribe func(id string) error, storeIDs []string, concurrency int, retries int, done func(bulkSubResult)) {
+	jobs := make(chan string)
+	completed := make(chan bulkSubResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				completed <- subscribeWithRetry(subscribe, id, retries, time.Second)
+			}
+		}()
+	}
+
+	go func() {
+		for _, id := range storeIDs {
+			jobs <- id
+		}
+		close(jobs)
+		wg.Wait()
+		close(completed)
+	}()
+
+	for result := range completed {
+		done(result)
+	}
+}
+
+// subscribeStore returns a function that subscribes to a single store using the RPC client
+func subscribeStore(client *rpc.Client) func(id string) error {
+	return func(id string) error {
+		slogs.Logr.Info("Subscription to store", "id", id)
+		if !storeIDRegex.MatchString(id) {
+			return permanentError{fmt.Errorf("%s is not a valid store ID", id)}
+		}
+		resp, _, err := client.DataLayerService.Subscribe(&rpc.DatalayerSubscribeOptions{
+			ID: id,
+		})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return permanentError{fmt.Errorf("data layer rejected the subscription: %s", resp.Error.OrElse("unknown error"))}
+		}
+		return nil
+	}
+}
+
+// subscribeWithRetry subscribes to a single store, retrying with exponential backoff starting at backoff on failure
+// A permanentError fails the store immediately
+func subscribeWithRetry(subscribe func(id string) error, id string, retries int, backoff time.Duration) bulkSubResult {
+	result := bulkSubResult{StoreID: id}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			slogs.Logr.Debug("Retrying subscription", "id", id, "attempt", attempt+1, "sleep", backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		result.Attempts = attempt + 1
+
+		err := subscribe(id)
+		if err != nil {
+			slogs.Logr.Error("Error subscribing to datastore", "id", id, "attempt", attempt+1, "error", err)
+			result.Status = bulkSubStatusFailed
+			result.Error = err.Error()
+			var permanent permanentError
+			if errors.As(err, &permanent) {
+				return result
+			}
+			continue
+		}
+
+		result.Status = bulkSubStatusSubscribed
+		result.Error = ""
+		return result
+	}
+
+	return result
+}
+
+// loadBulkSubResume reads the results file of a previous run and returns the store IDs that still need subscribing and the results that succeeded
+// The file has one result per line, and a later line for a store replaces an earlier one
+// Stores in storeIDs that have no result in the file were not reached by the previous run, and are returned as remaining too
+func loadBulkSubResume(file string, storeIDs []string) ([]string, []bulkSubResult, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	var order []string
+	latest := map[string]bulkSubResult{}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		result := bulkSubResult{}
+		err = json.Unmarshal(scanner.Bytes(), &result)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing line %d: %w", line, err)
+		}
+		if _, seen := latest[result.StoreID]; !seen {
+			order = append(order, result.StoreID)
+		}
+		latest[result.StoreID] = result
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var remaining []string
+	var succeeded []bulkSubResult
+	for _, id := range order {
+		if latest[id].Status == bulkSubStatusSubscribed {
+			succeeded = append(succeeded, latest[id])
+			continue
+		}
+		remaining = append(remaining, id)
+	}
+	for _, id := range storeIDs {
+		if _, seen := latest[id]; !seen {
+			remaining = append(remaining, id)
+			latest[id] = bulkSubResult{StoreID: id}
+		}
+	}
+
+	return remaining, succeeded, nil
+}
+
 func init() {
 	bulkSubCmd.PersistentFlags().StringP("file", "f", "", "The file containing the json of subscriptions to add")
+	bulkSubCmd.PersistentFlags().Int("concurrency", 4, "Number of subscriptions to process at the same time")
+	bulkSubCmd.PersistentFlags().Int("retries", 3, "Number of times to retry a failed subscription, with exponential backoff")
+	bulkSubCmd.PersistentFlags().String("results", "", "JSON Lines file to append the outcome for each store to as it completes (default is bulk-subscribe-results.jsonl, or the --resume file)")
+	bulkSubCmd.PersistentFlags().String("resume", "", "A JSON Lines results file from a previous run. Only the stores that failed, or that were in --file but not reached, will be subscribed")
 
 	cobra.CheckErr(viper.BindPFlag("bulksub-file", bulkSubCmd.PersistentFlags().Lookup("file")))
+	cobra.CheckErr(viper.BindPFlag("bulksub-concurrency", bulkSubCmd.PersistentFlags().Lookup("concurrency")))
+	cobra.CheckErr(viper.BindPFlag("bulksub-retries", bulkSubCmd.PersistentFlags().Lookup("retries")))
+	cobra.CheckErr(viper.BindPFlag("bulksub-results", bulkSubCmd.PersistentFlags().Lookup("results")))
+	cobra.CheckErr(viper.BindPFlag("bulksub-resume", bulkSubCmd.PersistentFlags().Lookup("resume")))
 
 	datalayerCmd.AddCommand(bulkSubCmd)
 }
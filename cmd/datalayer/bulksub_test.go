@@ -0,0 +1,91 @@
+package datalayer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func TestLoadBulkSubResume(t *testing.T) {
+	resultsFile := filepath.Join(t.TempDir(), "results.jsonl")
+	err := os.WriteFile(resultsFile, []byte(`{"store_id":"aa","status":"subscribed","attempts":1}
+{"store_id":"bb","status":"failed","attempts":4,"error":"timeout"}
+{"store_id":"cc","status":"failed","attempts":4,"error":"timeout"}
+
+{"store_id":"cc","status":"subscribed","attempts":2}
+`), 0644)
+	assert.NoError(t, err)
+
+	// dd was in the input but the previous run was interrupted before reaching it
+	remaining, succeeded, err := loadBulkSubResume(resultsFile, []string{"aa", "bb", "cc", "dd"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bb", "dd"}, remaining)
+	assert.Equal(t, []bulkSubResult{
+		{StoreID: "aa", Status: bulkSubStatusSubscribed, Attempts: 1},
+		{StoreID: "cc", Status: bulkSubStatusSubscribed, Attempts: 2},
+	}, succeeded)
+
+	remaining, _, err = loadBulkSubResume(resultsFile, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bb"}, remaining)
+
+	err = os.WriteFile(resultsFile, []byte("{\"store_id\":\"aa\"}\nnot json\n"), 0644)
+	assert.NoError(t, err)
+	_, _, err = loadBulkSubResume(resultsFile, nil)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestSubscribeWithRetry(t *testing.T) {
+	cmd.InitLogs()
+
+	calls := 0
+	result := subscribeWithRetry(func(id string) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, "aa", 3, 0)
+	assert.Equal(t, bulkSubResult{StoreID: "aa", Status: bulkSubStatusSubscribed, Attempts: 3}, result)
+
+	calls = 0
+	result = subscribeWithRetry(func(id string) error {
+		calls++
+		return errors.New("connection refused")
+	}, "bb", 2, 0)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, bulkSubResult{StoreID: "bb", Status: bulkSubStatusFailed, Attempts: 3, Error: "connection refused"}, result)
+
+	// Errors that retrying won't fix fail on the first attempt
+	calls = 0
+	result = subscribeWithRetry(func(id string) error {
+		calls++
+		return permanentError{errors.New("cc is not a valid store ID")}
+	}, "cc", 3, 0)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, bulkSubResult{StoreID: "cc", Status: bulkSubStatusFailed, Attempts: 1, Error: "cc is not a valid store ID"}, result)
+}
+
+func TestSubscribeAll(t *testing.T) {
+	cmd.InitLogs()
+
+	var results []bulkSubResult
+	subscribeAll(func(id string) error {
+		if id == "bb" {
+			return errors.New("not found")
+		}
+		return nil
+	}, []string{"aa", "bb", "cc"}, 2, 0, func(result bulkSubResult) {
+		results = append(results, result)
+	})
+	assert.ElementsMatch(t, []bulkSubResult{
+		{StoreID: "aa", Status: bulkSubStatusSubscribed, Attempts: 1},
+		{StoreID: "bb", Status: bulkSubStatusFailed, Attempts: 1, Error: "not found"},
+		{StoreID: "cc", Status: bulkSubStatusSubscribed, Attempts: 1},
+	}, results)
+}
@@ -0,0 +1,302 @@
+package datalayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// Mirror health states
+const (
+	mirrorReachable = "reachable"
+	mirrorStale     = "stale"
+	mirrorDead      = "dead"
+)
+
+// mirrorCheck is the health of a single mirror URL
+type mirrorCheck struct {
+	StoreID    string `json:"store_id"`
+	CoinID     string `json:"coin_id"`
+	Ours       bool   `json:"ours"`
+	URL        string `json:"url"`
+	Status     string `json:"status"`
+	Generation uint64 `json:"generation"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// checkMirrorsCmd checks that mirror URLs are serving the latest files for each store
+var checkMirrorsCmd = &cobra.Command{
+	Use:   "check-mirrors",
+	Short: "Checks that mirror URLs are serving the files for the latest root of each store",
+	Long: `Checks that mirror URLs are serving the files for the latest root of each store.
+
+Each URL is reported as reachable when it serves the full file for the latest generation,
+stale when it only serves the previous generation, and dead otherwise.`,
+	Example: `chik-tools data check-mirrors
+chik-tools data check-mirrors --all --as-json
+chik-tools data check-mirrors --id abcd1234
+
+# Delete owned mirrors where every URL is dead
+chik-tools data check-mirrors --delete-dead -m 0.00001`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		var storeIDs []string
+		switch {
+		case viper.GetString("check-mirrors-id") != "":
+			storeIDs = []string{viper.GetString("check-mirrors-id")}
+		case viper.GetBool("check-mirrors-all"):
+			subscriptions, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+			if err != nil {
+				slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
+			}
+			storeIDs = subscriptions.StoreIDs
+		default:
+			owned, _, err := client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
+			if err != nil {
+				slogs.Logr.Fatal("error getting list of owned data stores", "error", err)
+			}
+			storeIDs = owned.StoreIDs
+		}
+
+		httpClient := &http.Client{Timeout: viper.GetDuration("check-mirrors-timeout")}
+		var checks []mirrorCheck
+		deadMirrors := map[string][]types.DatalayerMirror{}
+		deadCount := 0
+		for _, storeID := range storeIDs {
+			storeChecks, dead, err := checkStoreMirrors(rpcMirrorSource{client: client}, httpClient, storeID)
+			if err != nil {
+				slogs.Logr.Fatal("error checking mirrors for store", "store", storeID, "error", err)
+			}
+			checks = append(checks, storeChecks...)
+			deadMirrors[storeID] = dead
+			deadCount += len(dead)
+		}
+
+		if viper.GetBool("check-mirrors-as-json") {
+			output, err := json.MarshalIndent(checks, "", "  ")
+			if err != nil {
+				slogs.Logr.Fatal("error marshaling output to JSON", "error", err)
+			}
+			fmt.Println(string(output))
+		} else {
+			printMirrorChecks(os.Stdout, checks)
+		}
+
+		if !viper.GetBool("check-mirrors-delete-dead") {
+			for _, check := range checks {
+				if check.Status == mirrorDead {
+					os.Exit(1)
+				}
+			}
+			return
+		}
+
+		if deadCount == 0 {
+			slogs.Logr.Info("No owned mirrors with only dead URLs")
+			return
+		}
+
+		feeMojos, err := resolveFee(client, viper.GetString("check-mirrors-fee"), viper.GetUint64("check-mirrors-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+		logFeeBudget(deadCount, feeMojos, 0)
+
+		dryRun := viper.GetBool("dry-run")
+		if !dryRun && !utils.ConfirmAction(fmt.Sprintf("Delete %d dead mirrors? (y/N)", deadCount), viper.GetBool("check-mirrors-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+		for _, storeID := range storeIDs {
+			if len(deadMirrors[storeID]) > 0 {
+				deleteMirrorsForSubscription(client, storeID, deadMirrors[storeID], feeMojos, dryRun)
+			}
+		}
+		if dryRun {
+			slogs.Logr.Info("DRY RUN: No changes were made")
+		}
+	},
+}
+
+// mirrorSource reads the root history and mirrors of a store
+type mirrorSource interface {
+	rootHistory(storeID string) ([]rootHistoryEntry, error)
+	mirrors(storeID string) ([]types.DatalayerMirror, error)
+}
+
+// rpcMirrorSource reads from the data layer RPC
+type rpcMirrorSource struct {
+	client *rpc.Client
+}
+
+func (s rpcMirrorSource) rootHistory(storeID string) ([]rootHistoryEntry, error) {
+	return getRootHistory(s.client, storeID)
+}
+
+func (s rpcMirrorSource) mirrors(storeID string) ([]types.DatalayerMirror, error) {
+	mirrors, _, err := s.client.DataLayerService.GetMirrors(&rpc.DatalayerGetMirrorsOptions{
+		ID: storeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mirrors.Mirrors, nil
+}
+
+// checkStoreMirrors checks every URL of every mirror for the store against its latest confirmed generation
+// A root that isn't confirmed yet hasn't been uploaded to the mirrors, so it would report every mirror as stale
+// Owned mirrors where every URL is dead are returned so they can be deleted
+func checkStoreMirrors(source mirrorSource, httpClient *http.Client, storeID string) ([]mirrorCheck, []types.DatalayerMirror, error) {
+	history, err := source.rootHistory(storeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	latest := latestConfirmedGeneration(history)
+	if latest < 1 {
+		slogs.Logr.Info("store has no data yet, skipping", "store", storeID)
+		return nil, nil, nil
+	}
+	generation := uint64(latest)
+
+	mirrors, err := source.mirrors(storeID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching mirrors: %w", err)
+	}
+
+	var checks []mirrorCheck
+	var dead []types.DatalayerMirror
+	for _, mirror := range mirrors {
+		allDead := true
+		for _, url := range mirror.URLs {
+			check := mirrorCheck{
+				StoreID:    storeID,
+				CoinID:     mirror.CoinID.String(),
+				Ours:       mirror.Ours,
+				URL:        url,
+				Generation: generation,
+			}
+
+			latency, err := probeServerFile(httpClient, url, storeID, history[generation].RootHash, generation)
+			check.LatencyMS = latency.Milliseconds()
+			switch {
+			case err == nil:
+				check.Status = mirrorReachable
+			case generation > 1:
+				check.Detail = err.Error()
+				if _, prevErr := probeServerFile(httpClient, url, storeID, history[generation-1].RootHash, generation-1); prevErr == nil {
+					check.Status = mirrorStale
+				} else {
+					check.Status = mirrorDead
+				}
+			default:
+				check.Detail = err.Error()
+				check.Status = mirrorDead
+			}
+			if check.Status != mirrorDead {
+				allDead = false
+			}
+			slogs.Logr.Debug("checked mirror", "store", storeID, "url", url, "status", check.Status, "latency_ms", check.LatencyMS)
+			checks = append(checks, check)
+		}
+		if mirror.Ours && allDead {
+			dead = append(dead, mirror)
+		}
+	}
+
+	return checks, dead, nil
+}
+
+// probeServerFile checks the mirror URL serves the full file for the generation, in either the flat or grouped by
+// store layout. The latency of the first request is returned
+func probeServerFile(httpClient *http.Client, baseURL, storeID, rootHash string, generation uint64) (time.Duration, error) {
+	var latency time.Duration
+	var lastErr error
+	for idx, groupByStore := range []bool{false, true} {
+		fileURL := strings.TrimSuffix(baseURL, "/") + "/" + serverFileName(storeID, rootHash, serverFileFull, generation, groupByStore)
+		start := time.Now()
+		err := probeURL(httpClient, fileURL)
+		if idx == 0 {
+			latency = time.Since(start)
+		}
+		if err == nil {
+			return latency, nil
+		}
+		lastErr = err
+	}
+	return latency, lastErr
+}
+
+// probeURL makes a HEAD request, falling back to a single byte GET for servers that don't support HEAD
+func probeURL(httpClient *http.Client, url string) error {
+	resp, err := httpClient.Head(url)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		resp, err = httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// printMirrorChecks writes the mirror checks as a table
+func printMirrorChecks(out io.Writer, checks []mirrorCheck) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STORE\tURL\tOURS\tSTATUS\tLATENCY\tDETAIL")
+	for _, check := range checks {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%dms\t%s\n", check.StoreID, check.URL, check.Ours, check.Status, check.LatencyMS, check.Detail)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	checkMirrorsCmd.PersistentFlags().String("id", "", "Only check mirrors for this store ID")
+	checkMirrorsCmd.PersistentFlags().Bool("all", false, "Check mirrors for all subscriptions instead of only owned stores")
+	checkMirrorsCmd.PersistentFlags().Duration("timeout", 10*time.Second, "Timeout for each HTTP request")
+	checkMirrorsCmd.PersistentFlags().Bool("as-json", false, "Output the results as JSON")
+	checkMirrorsCmd.PersistentFlags().Bool("delete-dead", false, "Delete owned mirrors where every URL is dead")
+	checkMirrorsCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation when deleting dead mirrors")
+	addFeeFlags(checkMirrorsCmd, "Fee to use when deleting dead mirrors. The fee is used per mirror")
+
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-id", checkMirrorsCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-all", checkMirrorsCmd.PersistentFlags().Lookup("all")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-timeout", checkMirrorsCmd.PersistentFlags().Lookup("timeout")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-as-json", checkMirrorsCmd.PersistentFlags().Lookup("as-json")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-delete-dead", checkMirrorsCmd.PersistentFlags().Lookup("delete-dead")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-yes", checkMirrorsCmd.PersistentFlags().Lookup("yes")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-fee", checkMirrorsCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("check-mirrors-fee-target-time", checkMirrorsCmd.PersistentFlags().Lookup("fee-target-time")))
+
+	datalayerCmd.AddCommand(checkMirrorsCmd)
+}
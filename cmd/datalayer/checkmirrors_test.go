@@ -0,0 +1,116 @@
+package datalayer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+// fakeMirrorSource serves the root history and mirrors of a store from memory
+type fakeMirrorSource struct {
+	history      []rootHistoryEntry
+	storeMirrors []types.DatalayerMirror
+}
+
+func (s fakeMirrorSource) rootHistory(storeID string) ([]rootHistoryEntry, error) {
+	return s.history, nil
+}
+
+func (s fakeMirrorSource) mirrors(storeID string) ([]types.DatalayerMirror, error) {
+	return s.storeMirrors, nil
+}
+
+func TestCheckStoreMirrors(t *testing.T) {
+	cmd.InitLogs()
+
+	storeID := strings.Repeat("ab", 32)
+	empty := "0x" + strings.Repeat("00", 32)
+	first := "0x" + strings.Repeat("11", 32)
+	second := "0x" + strings.Repeat("22", 32)
+	pending := "0x" + strings.Repeat("33", 32)
+	currentFile := "/" + serverFileName(storeID, second, serverFileFull, 2, false)
+	previousFile := "/" + serverFileName(storeID, first, serverFileFull, 1, false)
+	groupedFile := "/" + serverFileName(storeID, second, serverFileFull, 2, true)
+
+	// Each path prefix is a mirror with different files and behavior
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirror, file, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		file = "/" + file
+		found := false
+		switch mirror {
+		case "current":
+			found = file == currentFile
+		case "grouped":
+			found = file == groupedFile
+		case "previous":
+			found = file == previousFile
+		case "no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if file == currentFile && r.Header.Get("Range") == "bytes=0-0" {
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write([]byte{0})
+				return
+			}
+		}
+		if !found {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := fakeMirrorSource{
+		history: []rootHistoryEntry{
+			{RootHash: empty, Confirmed: true},
+			{RootHash: first, Confirmed: true},
+			{RootHash: second, Confirmed: true},
+			// Not confirmed yet, so not uploaded to the mirrors yet either
+			{RootHash: pending, Confirmed: false},
+		},
+		storeMirrors: []types.DatalayerMirror{
+			{CoinID: types.Bytes32{1}, Ours: true, URLs: []string{server.URL + "/current", server.URL + "/missing"}},
+			{CoinID: types.Bytes32{2}, Ours: true, URLs: []string{server.URL + "/grouped/", server.URL + "/no-head"}},
+			{CoinID: types.Bytes32{3}, Ours: true, URLs: []string{server.URL + "/previous"}},
+			{CoinID: types.Bytes32{4}, Ours: true, URLs: []string{server.URL + "/missing"}},
+			{CoinID: types.Bytes32{5}, Ours: false, URLs: []string{server.URL + "/missing"}},
+		},
+	}
+
+	checks, dead, err := checkStoreMirrors(source, server.Client(), storeID)
+	assert.NoError(t, err)
+	statuses := map[string]string{}
+	for _, check := range checks {
+		assert.Equal(t, uint64(2), check.Generation, check.URL)
+		statuses[check.CoinID+" "+strings.TrimPrefix(check.URL, server.URL)] = check.Status
+	}
+	coin := func(idx int) string {
+		return source.storeMirrors[idx].CoinID.String()
+	}
+	assert.Equal(t, map[string]string{
+		coin(0) + " /current":  mirrorReachable,
+		coin(0) + " /missing":  mirrorDead,
+		coin(1) + " /grouped/": mirrorReachable,
+		coin(1) + " /no-head":  mirrorReachable,
+		coin(2) + " /previous": mirrorStale,
+		coin(3) + " /missing":  mirrorDead,
+		coin(4) + " /missing":  mirrorDead,
+	}, statuses)
+
+	// Only our mirror where every URL is dead can be deleted. The first mirror still has a reachable URL
+	assert.Equal(t, []types.DatalayerMirror{source.storeMirrors[3]}, dead)
+
+	// A store without a confirmed generation beyond the empty root is skipped
+	source.history = source.history[:1]
+	checks, dead, err = checkStoreMirrors(source, server.Client(), storeID)
+	assert.NoError(t, err)
+	assert.Empty(t, checks)
+	assert.Empty(t, dead)
+}
@@ -1,9 +1,16 @@
 package datalayer
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/chik-network/go-chik-libs/pkg/rpc"
 	"github.com/chik-network/go-chik-libs/pkg/types"
@@ -12,12 +19,135 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Encodings keys and values can be converted between
+const (
+	formatHex    = "hex"
+	formatRawHex = "rawhex"
+	formatUTF8   = "utf8"
+	formatBase64 = "base64"
+	formatJSON   = "json"
+)
+
+// Streaming output types
+const (
+	streamNDJSON = "ndjson"
+	streamCSV    = "csv"
+)
+
+// strictFlagUsage is the usage of the --strict flag on every command that converts keys and values
+const strictFlagUsage = "Fail on keys or values that can't be encoded in the output format instead of writing them as hex"
+
+// defaultPageSize is the max_page_size used when paging through get_keys_values
+const defaultPageSize = 10 * 1024 * 1024
+
+// convertedKeyValue is a key value pair after conversion
+// Key and Value are strings, except for the json format where they are the decoded JSON
+type convertedKeyValue struct {
+	Atom  interface{} `json:"atom"`
+	Hash  string      `json:"hash"`
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// keyValueFormat holds the formats and filters used to convert keys and values
+// Unless Strict is set, a key or value that can't be encoded in the output format is written as 0x prefixed hex instead
+type keyValueFormat struct {
+	InputFormat  string
+	OutputFormat string
+	Prefix       []byte
+	Strict       bool
+}
+
+// newKeyValueFormat returns the conversion options
+// Prefixes starting with 0x are hex, otherwise they are used as text
+func newKeyValueFormat(inputFormat, outputFormat, prefix string, strict bool) (keyValueFormat, error) {
+	format := keyValueFormat{
+		InputFormat:  inputFormat,
+		OutputFormat: outputFormat,
+		Strict:       strict,
+	}
+	if strings.HasPrefix(prefix, "0x") {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(prefix, "0x"))
+		if err != nil {
+			return format, fmt.Errorf("invalid hex prefix: %w", err)
+		}
+		format.Prefix = decoded
+	} else {
+		format.Prefix = []byte(prefix)
+	}
+	return format, nil
+}
+
+// convert converts a key value pair. The second return value is false when the key doesn't match the prefix
+func (f keyValueFormat) convert(kv types.DatalayerKeyValue) (convertedKeyValue, bool, error) {
+	key, err := decodeFormat(kv.Key, f.InputFormat)
+	if err != nil {
+		return convertedKeyValue{}, false, fmt.Errorf("error decoding key %s: %w", kv.Key.String(), err)
+	}
+	if !bytes.HasPrefix(key, f.Prefix) {
+		return convertedKeyValue{}, false, nil
+	}
+
+	convertedKey, err := f.encode(key)
+	if err != nil {
+		return convertedKeyValue{}, false, fmt.Errorf("error converting key %s: %w", kv.Key.String(), err)
+	}
+	value, err := decodeFormat(kv.Value, f.InputFormat)
+	if err != nil {
+		return convertedKeyValue{}, false, fmt.Errorf("error decoding value for key %s: %w", kv.Key.String(), err)
+	}
+	convertedValue, err := f.encode(value)
+	if err != nil {
+		return convertedKeyValue{}, false, fmt.Errorf("error converting value for key %s: %w", kv.Key.String(), err)
+	}
+
+	return convertedKeyValue{
+		Atom:  kv.Atom,
+		Hash:  kv.Hash.String(),
+		Key:   convertedKey,
+		Value: convertedValue,
+	}, true, nil
+}
+
+// encode encodes raw bytes in the output format, falling back to hex when they can't be encoded and Strict isn't set
+func (f keyValueFormat) encode(input []byte) (interface{}, error) {
+	encoded, err := encodeFormat(input, f.OutputFormat)
+	if err != nil && !f.Strict && (f.OutputFormat == formatUTF8 || f.OutputFormat == formatJSON) {
+		slogs.Logr.Debug("falling back to hex", "output", f.OutputFormat, "error", err)
+		return encodeFormat(input, formatHex)
+	}
+	return encoded, err
+}
+
 // convertKeysValuesCmd converts keys and values between different encoding formats
 var convertKeysValuesCmd = &cobra.Command{
 	Use:   "convert-keys-values",
 	Short: "Converts keys and values from the Chik DataLayer get_keys_values endpoint between different encoding formats",
+	Long: `Converts keys and values from the Chik DataLayer get_keys_values endpoint between different encoding formats.
+
+Input formats describe how keys and values were encoded when they were inserted:
+  hex, utf8  the bytes are used as they are stored
+  rawhex     the stored bytes are hex text, which is decoded first
+  base64     the stored bytes are base64 text, which is decoded first
+
+Output formats:
+  hex     0x prefixed hex
+  rawhex  hex without the 0x prefix
+  utf8    UTF-8 text
+  base64  standard base64
+  json    the bytes are parsed as JSON and embedded in the output
+
+Keys and values that aren't valid UTF-8 or JSON are written as 0x prefixed hex instead.
+Use --strict to fail on them.`,
 	Example: `chik-tools data convert-keys-values --id abc123 --input-format hex --output-format utf8
-chik-tools data convert-keys-values --id abc123 --input-format utf8 --output-format hex`,
+chik-tools data convert-keys-values --id abc123 --input-format utf8 --output-format hex
+
+# Read a previous generation, only including keys that start with "user:"
+chik-tools data convert-keys-values --id abc123 --root-hash 0xdef456 --prefix user:
+
+# Stream a large store as newline delimited JSON or CSV
+chik-tools data convert-keys-values --id abc123 --output-format json --stream ndjson
+chik-tools data convert-keys-values --id abc123 --stream csv > store.csv`,
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
 		if err != nil {
@@ -29,145 +159,189 @@ chik-tools data convert-keys-values --id abc123 --input-format utf8 --output-for
 			slogs.Logr.Fatal("store ID is required")
 		}
 
-		// DEBUG: Log the store ID being used
-		slogs.Logr.Debug("Processing store", "id", storeID)
+		format, err := newKeyValueFormat(viper.GetString("input-format"), viper.GetString("output-format"), viper.GetString("convert-prefix"), viper.GetBool("convert-strict"))
+		if err != nil {
+			slogs.Logr.Fatal("invalid conversion options", "error", err)
+		}
+		slogs.Logr.Debug("Conversion formats", "store", storeID, "input", format.InputFormat, "output", format.OutputFormat)
+
+		var writer keyValueWriter
+		switch stream := viper.GetString("convert-stream"); stream {
+		case "":
+			writer = &jsonKeyValueWriter{out: os.Stdout}
+		case streamNDJSON:
+			writer = &ndjsonKeyValueWriter{encoder: json.NewEncoder(os.Stdout)}
+		case streamCSV:
+			writer = newCSVKeyValueWriter(os.Stdout)
+		default:
+			slogs.Logr.Fatal("unsupported stream type. Must be ndjson or csv", "stream", stream)
+		}
 
-		// Get keys and values from the datalayer
-		keysValues, _, err := client.DataLayerService.GetKeysValues(&rpc.DatalayerGetKeysValuesOptions{
-			ID: storeID,
+		err = forEachKeyValue(client, storeID, viper.GetString("convert-root-hash"), viper.GetInt("convert-page-size"), func(kv types.DatalayerKeyValue) error {
+			converted, ok, err := format.convert(kv)
+			if err != nil || !ok {
+				return err
+			}
+			return writer.write(converted)
 		})
 		if err != nil {
 			slogs.Logr.Fatal("error getting keys and values", "error", err)
 		}
 
-		// Convert the keys and values
-		inputFormat := viper.GetString("input-format")
-		outputFormat := viper.GetString("output-format")
-
-		// DEBUG: Log the conversion formats being used
-		slogs.Logr.Debug("Conversion formats", "input", inputFormat, "output", outputFormat)
-
-		// Create output structure that matches Chik DataLayer RPC format
-		output := struct {
-			KeysValues []struct {
-				Atom  interface{} `json:"atom"`
-				Hash  string      `json:"hash"`
-				Key   string      `json:"key"`
-				Value string      `json:"value"`
-			} `json:"keys_values"`
-			Success bool `json:"success"`
-		}{
-			KeysValues: make([]struct {
-				Atom  interface{} `json:"atom"`
-				Hash  string      `json:"hash"`
-				Key   string      `json:"key"`
-				Value string      `json:"value"`
-			}, 0),
-			Success: keysValues.Success,
+		err = writer.close()
+		if err != nil {
+			slogs.Logr.Fatal("error writing output", "error", err)
 		}
+	},
+}
 
-		// Convert each key-value pair
-		for _, kv := range keysValues.KeysValues {
-			// DEBUG: Log the original values
-			slogs.Logr.Debug("Original values",
-				"key_hex", fmt.Sprintf("%x", kv.Key),
-				"key_type", fmt.Sprintf("%T", kv.Key),
-				"value_hex", fmt.Sprintf("%x", kv.Value),
-				"value_type", fmt.Sprintf("%T", kv.Value))
-
-			// Convert key
-			convertedKey, err := convertFormat(kv.Key, inputFormat, outputFormat)
-			if err != nil {
-				slogs.Logr.Fatal("error converting key", "error", err)
-			}
+// decodeFormat returns the raw bytes for a key or value stored in the given format
+func decodeFormat(input types.Bytes, fromFormat string) ([]byte, error) {
+	switch fromFormat {
+	case formatHex, formatUTF8, formatJSON:
+		return input, nil
+	case formatRawHex:
+		return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(input)), "0x"))
+	case formatBase64:
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(input)))
+	default:
+		return nil, fmt.Errorf("unsupported input format %s", fromFormat)
+	}
+}
 
-			// Convert value
-			convertedValue, err := convertFormat(kv.Value, inputFormat, outputFormat)
-			if err != nil {
-				slogs.Logr.Fatal("error converting value", "error", err)
-			}
+// encodeFormat encodes raw bytes in the given format
+func encodeFormat(input []byte, toFormat string) (interface{}, error) {
+	switch toFormat {
+	case formatHex:
+		return "0x" + hex.EncodeToString(input), nil
+	case formatRawHex:
+		return hex.EncodeToString(input), nil
+	case formatUTF8:
+		if !utf8.Valid(input) {
+			return nil, fmt.Errorf("not valid UTF-8")
+		}
+		return string(input), nil
+	case formatBase64:
+		return base64.StdEncoding.EncodeToString(input), nil
+	case formatJSON:
+		if !json.Valid(input) {
+			return nil, fmt.Errorf("not valid JSON")
+		}
+		return json.RawMessage(input), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", toFormat)
+	}
+}
 
-			// DEBUG: Log the converted values
-			slogs.Logr.Debug("Converted values",
-				"key", convertedKey,
-				"key_type", fmt.Sprintf("%T", convertedKey),
-				"value", convertedValue,
-				"value_type", fmt.Sprintf("%T", convertedValue))
-
-			// Create new key-value pair with converted values
-			newKV := struct {
-				Atom  interface{} `json:"atom"`
-				Hash  string      `json:"hash"`
-				Key   string      `json:"key"`
-				Value string      `json:"value"`
-			}{
-				Atom:  kv.Atom,
-				Hash:  kv.Hash.String(), // Use the hash from the input, which should already be in the correct format
-				Key:   convertedKey,     // Use converted key directly
-				Value: convertedValue,   // Use converted value directly
-			}
+// keyValueWriter writes converted key value pairs in one of the output types
+type keyValueWriter interface {
+	write(kv convertedKeyValue) error
+	close() error
+}
 
-			// DEBUG: Log the new key-value pair
-			slogs.Logr.Debug("New key-value pair",
-				"key", newKV.Key,
-				"key_type", fmt.Sprintf("%T", newKV.Key),
-				"value", newKV.Value,
-				"value_type", fmt.Sprintf("%T", newKV.Value))
+// jsonKeyValueWriter collects every pair and writes them as one JSON document matching the get_keys_values response
+type jsonKeyValueWriter struct {
+	out        io.Writer
+	keysValues []convertedKeyValue
+}
 
-			output.KeysValues = append(output.KeysValues, newKV)
-		}
+func (w *jsonKeyValueWriter) write(kv convertedKeyValue) error {
+	w.keysValues = append(w.keysValues, kv)
+	return nil
+}
 
-		// Convert to JSON with nice formatting
-		jsonOutput, err := json.MarshalIndent(output, "", "  ")
-		if err != nil {
-			slogs.Logr.Fatal("error marshaling output to JSON", "error", err)
-		}
+func (w *jsonKeyValueWriter) close() error {
+	output := struct {
+		KeysValues []convertedKeyValue `json:"keys_values"`
+		Success    bool                `json:"success"`
+	}{
+		KeysValues: make([]convertedKeyValue, 0, len(w.keysValues)),
+		Success:    true,
+	}
+	output.KeysValues = append(output.KeysValues, w.keysValues...)
 
-		// DEBUG: Log the final JSON output
-		slogs.Logr.Debug("Final JSON output", "output", string(jsonOutput))
+	jsonOutput, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w.out, string(jsonOutput))
+	return err
+}
 
-		fmt.Println(string(jsonOutput))
-	},
+// ndjsonKeyValueWriter writes each pair as a JSON object on its own line as soon as it is converted
+type ndjsonKeyValueWriter struct {
+	encoder *json.Encoder
 }
 
-// convertFormat converts a string from one format to another
-func convertFormat(input types.Bytes, fromFormat, toFormat string) (string, error) {
-	// DEBUG: Log the input string and formats
-	slogs.Logr.Debug("Converting format",
-		"from", fromFormat,
-		"to", toFormat,
-		"input_type", fmt.Sprintf("%T", input),
-		"input_hex", fmt.Sprintf("%x", input))
+func (w *ndjsonKeyValueWriter) write(kv convertedKeyValue) error {
+	return w.encoder.Encode(kv)
+}
 
-	switch {
-	case fromFormat == toFormat:
-		return string(input), nil
+func (w *ndjsonKeyValueWriter) close() error {
+	return nil
+}
 
-	case fromFormat == "hex" && toFormat == "utf8":
-		// Convert bytes directly to string
-		result := string(input)
-		// DEBUG: Log the final UTF-8 string
-		slogs.Logr.Debug("Final UTF-8 string", "utf8", result)
-		return result, nil
+// csvKeyValueWriter writes each pair as a CSV row as soon as it is converted
+type csvKeyValueWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newCSVKeyValueWriter(out io.Writer) *csvKeyValueWriter {
+	return &csvKeyValueWriter{writer: csv.NewWriter(out)}
+}
 
-	case fromFormat == "utf8" && toFormat == "hex":
-		// Convert UTF-8 string to hex
-		result := "0x" + hex.EncodeToString(input)
-		return result, nil
+func (w *csvKeyValueWriter) write(kv convertedKeyValue) error {
+	if !w.headerWritten {
+		if err := w.writer.Write([]string{"key", "value", "hash"}); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	err := w.writer.Write([]string{csvField(kv.Key), csvField(kv.Value), kv.Hash})
+	if err != nil {
+		return err
+	}
+	// Flush each row so output streams rather than building up in the buffer
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvKeyValueWriter) close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
 
+// csvField returns the text for a converted key or value
+func csvField(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case json.RawMessage:
+		return string(value)
 	default:
-		return "", fmt.Errorf("unsupported conversion from %s to %s", fromFormat, toFormat)
+		return fmt.Sprintf("%v", value)
 	}
 }
 
 func init() {
 	convertKeysValuesCmd.PersistentFlags().String("id", "", "The store ID to convert keys and values for")
-	convertKeysValuesCmd.PersistentFlags().String("input-format", "hex", "Input format (hex, utf8)")
-	convertKeysValuesCmd.PersistentFlags().String("output-format", "utf8", "Output format (hex, utf8)")
+	convertKeysValuesCmd.PersistentFlags().String("input-format", "hex", "Input format (hex, rawhex, utf8, base64)")
+	convertKeysValuesCmd.PersistentFlags().String("output-format", "utf8", "Output format (hex, rawhex, utf8, base64, json)")
+	convertKeysValuesCmd.PersistentFlags().String("root-hash", "", "Read keys and values at this root hash instead of the current root")
+	convertKeysValuesCmd.PersistentFlags().String("prefix", "", "Only include keys that start with this prefix, after decoding the input format. Use 0x for a hex prefix")
+	convertKeysValuesCmd.PersistentFlags().String("stream", "", "Stream each page of output as it is read instead of a single JSON document (ndjson, csv)")
+	convertKeysValuesCmd.PersistentFlags().Int("page-size", defaultPageSize, "Maximum size in bytes of each page requested from get_keys_values")
+	convertKeysValuesCmd.PersistentFlags().Bool("strict", false, strictFlagUsage)
 
 	cobra.CheckErr(viper.BindPFlag("convert-id", convertKeysValuesCmd.PersistentFlags().Lookup("id")))
 	cobra.CheckErr(viper.BindPFlag("input-format", convertKeysValuesCmd.PersistentFlags().Lookup("input-format")))
 	cobra.CheckErr(viper.BindPFlag("output-format", convertKeysValuesCmd.PersistentFlags().Lookup("output-format")))
+	cobra.CheckErr(viper.BindPFlag("convert-root-hash", convertKeysValuesCmd.PersistentFlags().Lookup("root-hash")))
+	cobra.CheckErr(viper.BindPFlag("convert-prefix", convertKeysValuesCmd.PersistentFlags().Lookup("prefix")))
+	cobra.CheckErr(viper.BindPFlag("convert-stream", convertKeysValuesCmd.PersistentFlags().Lookup("stream")))
+	cobra.CheckErr(viper.BindPFlag("convert-page-size", convertKeysValuesCmd.PersistentFlags().Lookup("page-size")))
+	cobra.CheckErr(viper.BindPFlag("convert-strict", convertKeysValuesCmd.PersistentFlags().Lookup("strict")))
 
 	datalayerCmd.AddCommand(convertKeysValuesCmd)
 }
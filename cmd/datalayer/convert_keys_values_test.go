@@ -0,0 +1,78 @@
+package datalayer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func TestConvertFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		from     string
+		to       string
+		expected interface{}
+	}{
+		{"hello", formatHex, formatUTF8, "hello"},
+		{"hello", formatUTF8, formatHex, "0x68656c6c6f"},
+		{"hello", formatHex, formatRawHex, "68656c6c6f"},
+		{"hello", formatHex, formatBase64, "aGVsbG8="},
+		{"aGVsbG8=", formatBase64, formatUTF8, "hello"},
+		{"0x68656c6c6f", formatRawHex, formatUTF8, "hello"},
+		{`{"a":1}`, formatHex, formatJSON, json.RawMessage(`{"a":1}`)},
+	}
+	for _, tt := range tests {
+		format := keyValueFormat{InputFormat: tt.from, OutputFormat: tt.to, Strict: true}
+		converted, ok, err := format.convert(types.DatalayerKeyValue{Key: types.Bytes(tt.input), Value: types.Bytes(tt.input)})
+		assert.NoError(t, err, tt.input)
+		assert.True(t, ok, tt.input)
+		assert.Equal(t, tt.expected, converted.Key, tt.input)
+		assert.Equal(t, tt.expected, converted.Value, tt.input)
+	}
+}
+
+func TestKeyValueFormatFallback(t *testing.T) {
+	cmd.InitLogs()
+	binary := types.DatalayerKeyValue{Key: types.Bytes("bin"), Value: types.Bytes{0xff, 0xfe}}
+	notJSON := types.DatalayerKeyValue{Key: types.Bytes(`"doc"`), Value: types.Bytes("not json")}
+
+	// Only the field that can't be encoded falls back to hex
+	format := keyValueFormat{InputFormat: formatHex, OutputFormat: formatUTF8}
+	converted, ok, err := format.convert(binary)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bin", converted.Key)
+	assert.Equal(t, "0xfffe", converted.Value)
+
+	format.OutputFormat = formatJSON
+	converted, _, err = format.convert(notJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, json.RawMessage(`"doc"`), converted.Key)
+	assert.Equal(t, "0x6e6f74206a736f6e", converted.Value)
+
+	// Strict conversion fails instead
+	format.Strict = true
+	_, _, err = format.convert(notJSON)
+	assert.Error(t, err)
+	format.OutputFormat = formatUTF8
+	_, _, err = format.convert(binary)
+	assert.Error(t, err)
+}
+
+func TestKeyValueFormatPrefix(t *testing.T) {
+	format := keyValueFormat{InputFormat: formatHex, OutputFormat: formatUTF8, Prefix: []byte("user:")}
+
+	converted, ok, err := format.convert(types.DatalayerKeyValue{Key: types.Bytes("user:1"), Value: types.Bytes("alice")})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "user:1", converted.Key)
+	assert.Equal(t, "alice", converted.Value)
+
+	_, ok, err = format.convert(types.DatalayerKeyValue{Key: types.Bytes("group:1"), Value: types.Bytes("admins")})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
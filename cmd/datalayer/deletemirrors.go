@@ -8,6 +8,8 @@ import (
 	"github.com/chik-network/go-modules/pkg/slogs"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
 )
 
 // deleteMirrorsCmd Deletes all owned mirrors for all datalayer subscriptions
@@ -17,6 +19,10 @@ var deleteMirrorsCmd = &cobra.Command{
 	Example: `chik-tools data delete-mirrors --all
 chik-tools data delete-mirrors --id abcd1234
 
+# Fees can be given in XCK, in mojos, or estimated by the full node
+chik-tools data delete-mirrors --all --fee 100mojos
+chik-tools data delete-mirrors --all --fee auto --fee-target-time 120
+
 # Show what changes would be made without actually making them
 chik-tools data delete-mirrors --id abcd1234 --dry-run`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -34,42 +40,50 @@ chik-tools data delete-mirrors --id abcd1234 --dry-run`,
 		}
 
 		// Figure out what fee we are using
-		feeXCK := viper.GetFloat64("delete-mirror-fee")
-		feeMojos := uint64(feeXCK * 1000000000000)
-		slogs.Logr.Info("fee for all transactions", "xck", feeXCK, "mojos", feeMojos)
+		feeMojos, err := resolveFee(client, viper.GetString("delete-mirror-fee"), viper.GetUint64("delete-mirror-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+		slogs.Logr.Info("fee for all transactions", "xck", utils.FormatXCK(feeMojos), "mojos", feeMojos)
 
 		all := viper.GetBool("delete-mirror-all")
 		subID := viper.GetString("delete-mirror-id")
 		dryRun := viper.GetBool("dry-run")
 
-		if dryRun {
-			slogs.Logr.Info("DRY RUN: Would delete mirrors")
-			if all {
-				slogs.Logr.Info("DRY RUN: Would delete all owned mirrors for all subscriptions")
-			} else {
-				slogs.Logr.Info("DRY RUN: Would delete mirrors for subscription", "id", subID)
-			}
-			slogs.Logr.Info("DRY RUN: No changes were made")
-			return
-		}
-
+		subscriptions := []string{subID}
 		if all {
 			slogs.Logr.Info("deleting all owned mirrors for all subscriptions")
-			subscriptions, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+			resp, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
 			if err != nil {
 				slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
 			}
+			subscriptions = resp.StoreIDs
+		}
 
-			for _, subscription := range subscriptions.StoreIDs {
-				deleteMirrorsForSubscription(client, subscription, feeMojos, dryRun)
+		ownedMirrors := map[string][]types.DatalayerMirror{}
+		count := 0
+		for _, subscription := range subscriptions {
+			mirrors := ownedMirrorsForSubscription(client, subscription)
+			if len(mirrors) == 0 {
+				continue
 			}
-		} else {
-			deleteMirrorsForSubscription(client, subID, feeMojos, dryRun)
+			ownedMirrors[subscription] = mirrors
+			count += len(mirrors)
+		}
+		logFeeBudget(count, feeMojos, 0)
+
+		for _, subscription := range subscriptions {
+			deleteMirrorsForSubscription(client, subscription, ownedMirrors[subscription], feeMojos, dryRun)
+		}
+
+		if dryRun {
+			slogs.Logr.Info("DRY RUN: No changes were made")
 		}
 	},
 }
 
-func deleteMirrorsForSubscription(client *rpc.Client, subscription string, feeMojos uint64, dryRun bool) {
+// ownedMirrorsForSubscription returns the mirrors for the store that are owned by this wallet
+func ownedMirrorsForSubscription(client *rpc.Client, subscription string) []types.DatalayerMirror {
 	slogs.Logr.Info("checking subscription", "store", subscription)
 
 	mirrors, _, err := client.DataLayerService.GetMirrors(&rpc.DatalayerGetMirrorsOptions{
@@ -86,6 +100,10 @@ func deleteMirrorsForSubscription(client *rpc.Client, subscription string, feeMo
 		}
 	}
 
+	return ownedMirrors
+}
+
+func deleteMirrorsForSubscription(client *rpc.Client, subscription string, ownedMirrors []types.DatalayerMirror, feeMojos uint64, dryRun bool) {
 	if len(ownedMirrors) == 0 {
 		slogs.Logr.Info("no owned mirrors for this datastore", "store", subscription)
 		return
@@ -103,6 +121,7 @@ func deleteMirrorsForSubscription(client *rpc.Client, subscription string, feeMo
 	}
 
 	for _, mirror := range ownedMirrors {
+		waitForAvailableBalance(client, feeMojos)
 		slogs.Logr.Info("deleting mirror",
 			"store", subscription,
 			"coin_id", mirror.CoinID.String(),
@@ -121,11 +140,12 @@ func deleteMirrorsForSubscription(client *rpc.Client, subscription string, feeMo
 }
 
 func init() {
-	deleteMirrorsCmd.PersistentFlags().Float64P("fee", "m", 0, "Fee to use when deleting the mirrors. The fee is used per mirror. Units are XCK")
+	addFeeFlags(deleteMirrorsCmd, "Fee to use when deleting the mirrors. The fee is used per mirror")
 	deleteMirrorsCmd.PersistentFlags().Bool("all", false, "Delete all owned mirrors for all subscriptions")
 	deleteMirrorsCmd.PersistentFlags().String("id", "", "The subscription ID to delete mirrors for")
 
 	cobra.CheckErr(viper.BindPFlag("delete-mirror-fee", deleteMirrorsCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("delete-mirror-fee-target-time", deleteMirrorsCmd.PersistentFlags().Lookup("fee-target-time")))
 	cobra.CheckErr(viper.BindPFlag("delete-mirror-all", deleteMirrorsCmd.PersistentFlags().Lookup("all")))
 	cobra.CheckErr(viper.BindPFlag("delete-mirror-id", deleteMirrorsCmd.PersistentFlags().Lookup("id")))
 
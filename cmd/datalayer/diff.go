@@ -0,0 +1,290 @@
+package datalayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// rootRef identifies a single generation of a store
+type rootRef struct {
+	Generation uint64 `json:"generation"`
+	RootHash   string `json:"root_hash"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// diffChange is a single inserted or deleted key after conversion
+type diffChange struct {
+	Type  string      `json:"type"`
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// generationDiff is every change between two generations of a store
+type generationDiff struct {
+	StoreID string       `json:"store_id"`
+	From    rootRef      `json:"from"`
+	To      rootRef      `json:"to"`
+	Changes []diffChange `json:"changes"`
+}
+
+// diffCmd shows the keys and values that changed between two generations of a store
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows the keys and values that changed between two generations of a store",
+	Example: `# Compare the latest generation with the one before it
+chik-tools data diff --id abcd1234
+
+# Compare two generations, or two root hashes
+chik-tools data diff --id abcd1234 --from 3 --to 7
+chik-tools data diff --id abcd1234 --from 0xdef456 --to 0x789abc --as-json
+
+# Print the changes in every generation of the store
+chik-tools data diff --id abcd1234 --changelog --output-format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		storeID := viper.GetString("diff-id")
+		if storeID == "" {
+			slogs.Logr.Fatal("store ID is required")
+		}
+
+		format, err := newKeyValueFormat(viper.GetString("diff-input-format"), viper.GetString("diff-output-format"), viper.GetString("diff-prefix"), viper.GetBool("diff-strict"))
+		if err != nil {
+			slogs.Logr.Fatal("invalid conversion options", "error", err)
+		}
+
+		history, err := getRootHistory(client, storeID)
+		if err != nil {
+			slogs.Logr.Fatal("error getting root history for store", "store", storeID, "error", err)
+		}
+		if len(history) < 2 {
+			slogs.Logr.Fatal("store does not have any generations to compare", "store", storeID)
+		}
+
+		var pairs [][2]rootRef
+		if viper.GetBool("diff-changelog") {
+			for generation := 1; generation < len(history); generation++ {
+				pairs = append(pairs, [2]rootRef{historyRef(history, uint64(generation-1)), historyRef(history, uint64(generation))})
+			}
+		} else {
+			to := historyRef(history, uint64(len(history)-1))
+			if toValue := viper.GetString("diff-to"); toValue != "" {
+				to, err = resolveRootRef(history, toValue)
+				if err != nil {
+					slogs.Logr.Fatal("invalid --to", "error", err)
+				}
+			}
+			from := historyRef(history, 0)
+			if to.Generation > 0 {
+				from = historyRef(history, to.Generation-1)
+			}
+			if fromValue := viper.GetString("diff-from"); fromValue != "" {
+				from, err = resolveRootRef(history, fromValue)
+				if err != nil {
+					slogs.Logr.Fatal("invalid --from", "error", err)
+				}
+			}
+			pairs = append(pairs, [2]rootRef{from, to})
+		}
+
+		var diffs []*generationDiff
+		for _, pair := range pairs {
+			diff, err := computeGenerationDiff(rpcDiffSource{client: client, pageSize: viper.GetInt("diff-page-size")}, storeID, pair[0], pair[1], format)
+			if err != nil {
+				slogs.Logr.Fatal("error computing diff", "store", storeID, "from", pair[0].Generation, "to", pair[1].Generation, "error", err)
+			}
+			diffs = append(diffs, diff)
+		}
+
+		if viper.GetBool("diff-as-json") {
+			var output interface{} = diffs
+			if len(diffs) == 1 && !viper.GetBool("diff-changelog") {
+				output = diffs[0]
+			}
+			jsonOutput, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				slogs.Logr.Fatal("error marshaling output to JSON", "error", err)
+			}
+			fmt.Println(string(jsonOutput))
+			return
+		}
+
+		for _, diff := range diffs {
+			printDiffText(os.Stdout, diff)
+		}
+	},
+}
+
+// historyRef returns the generation from the root history
+func historyRef(history []rootHistoryEntry, generation uint64) rootRef {
+	return rootRef{
+		Generation: generation,
+		RootHash:   history[generation].RootHash,
+		Timestamp:  history[generation].Timestamp,
+	}
+}
+
+// resolveRootRef finds a generation in the root history by generation number or root hash
+func resolveRootRef(history []rootHistoryEntry, value string) (rootRef, error) {
+	if generation, err := strconv.ParseUint(value, 10, 64); err == nil && len(value) < 64 {
+		if generation >= uint64(len(history)) {
+			return rootRef{}, fmt.Errorf("generation %d does not exist. The latest generation is %d", generation, len(history)-1)
+		}
+		return historyRef(history, generation), nil
+	}
+
+	for idx, entry := range history {
+		if strings.EqualFold(strings.TrimPrefix(entry.RootHash, "0x"), strings.TrimPrefix(value, "0x")) {
+			return historyRef(history, uint64(idx)), nil
+		}
+	}
+	return rootRef{}, fmt.Errorf("root hash %s is not in the history of the store", value)
+}
+
+// isEmptyRoot returns true for the root hash of a store without any keys
+func isEmptyRoot(rootHash string) bool {
+	return strings.Trim(strings.TrimPrefix(rootHash, "0x"), "0") == ""
+}
+
+// diffSource reads the keys and values of a store that are needed to compare two of its roots
+type diffSource interface {
+	forEachKeyValue(storeID, rootHash string, fn func(types.DatalayerKeyValue) error) error
+	kvDiff(storeID, fromHash, toHash string) ([]kvDiffEntry, error)
+}
+
+// rpcDiffSource reads from the data layer RPC, in pages of at most pageSize bytes
+type rpcDiffSource struct {
+	client   *rpc.Client
+	pageSize int
+}
+
+func (s rpcDiffSource) forEachKeyValue(storeID, rootHash string, fn func(types.DatalayerKeyValue) error) error {
+	return forEachKeyValue(s.client, storeID, rootHash, s.pageSize, fn)
+}
+
+func (s rpcDiffSource) kvDiff(storeID, fromHash, toHash string) ([]kvDiffEntry, error) {
+	return getKVDiff(s.client, storeID, fromHash, toHash, s.pageSize)
+}
+
+// computeGenerationDiff returns the converted changes between two generations, sorted by key with deletes first
+func computeGenerationDiff(source diffSource, storeID string, from, to rootRef, format keyValueFormat) (*generationDiff, error) {
+	var entries []kvDiffEntry
+	switch {
+	case from.RootHash == to.RootHash:
+	case isEmptyRoot(from.RootHash) && isEmptyRoot(to.RootHash):
+	case isEmptyRoot(from.RootHash):
+		// Everything in the later generation is new
+		err := source.forEachKeyValue(storeID, to.RootHash, func(kv types.DatalayerKeyValue) error {
+			entries = append(entries, kvDiffEntry{Type: diffInsert, Key: kv.Key, Value: kv.Value})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	case isEmptyRoot(to.RootHash):
+		// Every key was deleted, which leaves the later generation without a root to diff against
+		err := source.forEachKeyValue(storeID, from.RootHash, func(kv types.DatalayerKeyValue) error {
+			entries = append(entries, kvDiffEntry{Type: diffDelete, Key: kv.Key, Value: kv.Value})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		var err error
+		entries, err = source.kvDiff(storeID, from.RootHash, to.RootHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	diff := &generationDiff{StoreID: storeID, From: from, To: to, Changes: []diffChange{}}
+	for _, entry := range entries {
+		converted, ok, err := format.convert(types.DatalayerKeyValue{Key: entry.Key, Value: entry.Value})
+		if err != nil {
+			// The same generation will fail to convert every time, so retrying won't help
+			return nil, permanentError{err}
+		}
+		if !ok {
+			continue
+		}
+		diff.Changes = append(diff.Changes, diffChange{
+			Type:  strings.ToLower(entry.Type),
+			Key:   converted.Key,
+			Value: converted.Value,
+		})
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		keyI, keyJ := csvField(diff.Changes[i].Key), csvField(diff.Changes[j].Key)
+		if keyI != keyJ {
+			return keyI < keyJ
+		}
+		return diff.Changes[i].Type == strings.ToLower(diffDelete) && diff.Changes[j].Type != strings.ToLower(diffDelete)
+	})
+
+	return diff, nil
+}
+
+// printDiffText writes the diff in the style of a unified diff
+func printDiffText(out io.Writer, diff *generationDiff) {
+	_, _ = fmt.Fprintf(out, "--- %s generation %d %s %s\n", diff.StoreID, diff.From.Generation, diff.From.RootHash, formatRootTimestamp(diff.From.Timestamp))
+	_, _ = fmt.Fprintf(out, "+++ %s generation %d %s %s\n", diff.StoreID, diff.To.Generation, diff.To.RootHash, formatRootTimestamp(diff.To.Timestamp))
+	for _, change := range diff.Changes {
+		marker := "+"
+		if change.Type == strings.ToLower(diffDelete) {
+			marker = "-"
+		}
+		_, _ = fmt.Fprintf(out, "%s%s: %s\n", marker, csvField(change.Key), csvField(change.Value))
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// formatRootTimestamp formats a root timestamp for display
+func formatRootTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+func init() {
+	diffCmd.PersistentFlags().String("id", "", "The store ID to compare generations of")
+	diffCmd.PersistentFlags().String("from", "", "Generation number or root hash to compare from (default is the generation before --to)")
+	diffCmd.PersistentFlags().String("to", "", "Generation number or root hash to compare to (default is the latest generation)")
+	diffCmd.PersistentFlags().Bool("changelog", false, "Print the changes made in every generation of the store")
+	diffCmd.PersistentFlags().Bool("as-json", false, "Output the diff as JSON")
+	diffCmd.PersistentFlags().String("input-format", "hex", "Input format (hex, rawhex, utf8, base64)")
+	diffCmd.PersistentFlags().String("output-format", "utf8", "Output format (hex, rawhex, utf8, base64, json)")
+	diffCmd.PersistentFlags().String("prefix", "", "Only include keys that start with this prefix, after decoding the input format. Use 0x for a hex prefix")
+	diffCmd.PersistentFlags().Bool("strict", false, strictFlagUsage)
+	diffCmd.PersistentFlags().Int("page-size", defaultPageSize, "Maximum size in bytes of each page requested from the data layer")
+
+	cobra.CheckErr(viper.BindPFlag("diff-id", diffCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("diff-from", diffCmd.PersistentFlags().Lookup("from")))
+	cobra.CheckErr(viper.BindPFlag("diff-to", diffCmd.PersistentFlags().Lookup("to")))
+	cobra.CheckErr(viper.BindPFlag("diff-changelog", diffCmd.PersistentFlags().Lookup("changelog")))
+	cobra.CheckErr(viper.BindPFlag("diff-as-json", diffCmd.PersistentFlags().Lookup("as-json")))
+	cobra.CheckErr(viper.BindPFlag("diff-input-format", diffCmd.PersistentFlags().Lookup("input-format")))
+	cobra.CheckErr(viper.BindPFlag("diff-output-format", diffCmd.PersistentFlags().Lookup("output-format")))
+	cobra.CheckErr(viper.BindPFlag("diff-prefix", diffCmd.PersistentFlags().Lookup("prefix")))
+	cobra.CheckErr(viper.BindPFlag("diff-strict", diffCmd.PersistentFlags().Lookup("strict")))
+	cobra.CheckErr(viper.BindPFlag("diff-page-size", diffCmd.PersistentFlags().Lookup("page-size")))
+
+	datalayerCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,95 @@
+package datalayer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRootRef(t *testing.T) {
+	history := []rootHistoryEntry{
+		{RootHash: "0x0000000000000000000000000000000000000000000000000000000000000000"},
+		{RootHash: "0xaaaa000000000000000000000000000000000000000000000000000000000000", Timestamp: 100},
+		{RootHash: "0xbbbb000000000000000000000000000000000000000000000000000000000000", Timestamp: 200},
+	}
+
+	ref, err := resolveRootRef(history, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, rootRef{Generation: 1, RootHash: history[1].RootHash, Timestamp: 100}, ref)
+
+	ref, err = resolveRootRef(history, "BBBB000000000000000000000000000000000000000000000000000000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), ref.Generation)
+
+	_, err = resolveRootRef(history, "3")
+	assert.Error(t, err)
+	_, err = resolveRootRef(history, "0xcccc")
+	assert.Error(t, err)
+
+	assert.True(t, isEmptyRoot(history[0].RootHash))
+	assert.False(t, isEmptyRoot(history[1].RootHash))
+}
+
+// fakeDiffSource serves the keys and values of each root from memory
+type fakeDiffSource struct {
+	roots map[string][]types.DatalayerKeyValue
+	diffs map[[2]string][]kvDiffEntry
+}
+
+func (s fakeDiffSource) forEachKeyValue(storeID, rootHash string, fn func(types.DatalayerKeyValue) error) error {
+	if isEmptyRoot(rootHash) {
+		return fmt.Errorf("no keys for empty root %s", rootHash)
+	}
+	for _, kv := range s.roots[rootHash] {
+		if err := fn(kv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s fakeDiffSource) kvDiff(storeID, fromHash, toHash string) ([]kvDiffEntry, error) {
+	if isEmptyRoot(fromHash) || isEmptyRoot(toHash) {
+		return nil, fmt.Errorf("unable to diff against an empty root")
+	}
+	return s.diffs[[2]string{fromHash, toHash}], nil
+}
+
+func TestComputeGenerationDiff(t *testing.T) {
+	empty := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	first := "0xaaaa000000000000000000000000000000000000000000000000000000000000"
+	second := "0xbbbb000000000000000000000000000000000000000000000000000000000000"
+	source := fakeDiffSource{
+		roots: map[string][]types.DatalayerKeyValue{
+			first: {{Key: types.Bytes("b"), Value: types.Bytes("2")}, {Key: types.Bytes("a"), Value: types.Bytes("1")}},
+		},
+		diffs: map[[2]string][]kvDiffEntry{
+			{first, second}: {{Type: diffInsert, Key: types.Bytes("a"), Value: types.Bytes("3")}, {Type: diffDelete, Key: types.Bytes("a"), Value: types.Bytes("1")}},
+		},
+	}
+	format := keyValueFormat{InputFormat: formatHex, OutputFormat: formatUTF8}
+	generation := func(n uint64, rootHash string) rootRef {
+		return rootRef{Generation: n, RootHash: rootHash}
+	}
+
+	diff, err := computeGenerationDiff(source, "abcd", generation(0, empty), generation(1, first), format)
+	assert.NoError(t, err)
+	assert.Equal(t, []diffChange{{Type: "insert", Key: "a", Value: "1"}, {Type: "insert", Key: "b", Value: "2"}}, diff.Changes)
+
+	diff, err = computeGenerationDiff(source, "abcd", generation(1, first), generation(2, second), format)
+	assert.NoError(t, err)
+	assert.Equal(t, []diffChange{{Type: "delete", Key: "a", Value: "1"}, {Type: "insert", Key: "a", Value: "3"}}, diff.Changes)
+
+	// A generation that deletes every key leaves the store with an empty root
+	diff, err = computeGenerationDiff(source, "abcd", generation(1, first), generation(2, empty), format)
+	assert.NoError(t, err)
+	assert.Equal(t, []diffChange{{Type: "delete", Key: "a", Value: "1"}, {Type: "delete", Key: "b", Value: "2"}}, diff.Changes)
+
+	// Empty roots are compared by value, since they may be written with or without the 0x prefix
+	diff, err = computeGenerationDiff(source, "abcd", generation(2, empty), generation(3, strings.TrimPrefix(empty, "0x")), format)
+	assert.NoError(t, err)
+	assert.Empty(t, diff.Changes)
+}
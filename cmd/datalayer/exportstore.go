@@ -0,0 +1,191 @@
+package datalayer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// storeExportVersion is the version of the export format written by export-store
+const storeExportVersion = 1
+
+// exportedKeyValue is a single line of a store export. Keys and values are 0x prefixed hex
+type exportedKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// storeExportManifest describes a store export, so it can be verified before importing
+type storeExportManifest struct {
+	Version    int       `json:"version"`
+	StoreID    string    `json:"store_id"`
+	RootHash   string    `json:"root_hash"`
+	Generation *uint64   `json:"generation,omitempty"`
+	DataFile   string    `json:"data_file"`
+	KeyCount   int       `json:"key_count"`
+	Bytes      int64     `json:"bytes"`
+	SHA256     string    `json:"sha256"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// exportStoreCmd writes every key and value in a store to a file
+var exportStoreCmd = &cobra.Command{
+	Use:   "export-store",
+	Short: "Exports every key and value in a store to an NDJSON file with a manifest",
+	Example: `chik-tools data export-store --id abcd1234
+
+# Export a previous generation to a specific file
+chik-tools data export-store --id abcd1234 --root-hash 0xdef456 -o backup.ndjson`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		storeID := viper.GetString("export-store-id")
+		if storeID == "" {
+			slogs.Logr.Fatal("store ID is required")
+		}
+
+		// Pin the root hash, so every page comes from the same generation even if the store is updated mid export
+		rootHash := viper.GetString("export-store-root-hash")
+		if rootHash == "" {
+			root, err := getRoot(client, storeID)
+			if err != nil {
+				slogs.Logr.Fatal("error getting current root for store", "store", storeID, "error", err)
+			}
+			rootHash = root.Hash
+		}
+
+		outFile := viper.GetString("export-store-output")
+		if outFile == "" {
+			outFile = fmt.Sprintf("%s.ndjson", strings.TrimPrefix(storeID, "0x"))
+		}
+
+		manifest, err := exportStore(client, storeID, rootHash, outFile, viper.GetInt("export-store-page-size"))
+		if err != nil {
+			slogs.Logr.Fatal("error exporting store", "store", storeID, "error", err)
+		}
+
+		history, err := getRootHistory(client, storeID)
+		if err != nil {
+			slogs.Logr.Warn("unable to determine generation for root hash", "error", err)
+		}
+		for idx, entry := range history {
+			if strings.EqualFold(strings.TrimPrefix(entry.RootHash, "0x"), strings.TrimPrefix(rootHash, "0x")) {
+				generation := uint64(idx)
+				manifest.Generation = &generation
+			}
+		}
+
+		err = writeStoreManifest(manifestPath(outFile), manifest)
+		if err != nil {
+			slogs.Logr.Fatal("error writing manifest", "error", err)
+		}
+		slogs.Logr.Info("Exported store", "store", storeID, "root_hash", rootHash, "keys", manifest.KeyCount, "file", outFile, "manifest", manifestPath(outFile))
+	},
+}
+
+// exportStore writes every key and value at the root hash to outFile and returns the manifest for the export
+func exportStore(client *rpc.Client, storeID, rootHash, outFile string, pageSize int) (*storeExportManifest, error) {
+	f, err := os.Create(outFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	buffered := bufio.NewWriter(io.MultiWriter(f, hash, counter))
+	encoder := json.NewEncoder(buffered)
+
+	manifest := &storeExportManifest{
+		Version:    storeExportVersion,
+		StoreID:    storeID,
+		RootHash:   rootHash,
+		DataFile:   filepath.Base(outFile),
+		ExportedAt: time.Now().UTC(),
+	}
+	err = forEachKeyValue(client, storeID, rootHash, pageSize, func(kv types.DatalayerKeyValue) error {
+		manifest.KeyCount++
+		return encoder.Encode(exportedKeyValue{Key: kv.Key.String(), Value: kv.Value.String()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = buffered.Flush(); err != nil {
+		return nil, err
+	}
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+
+	manifest.Bytes = counter.n
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return manifest, nil
+}
+
+// manifestPath returns the manifest file that goes with an export data file
+func manifestPath(dataFile string) string {
+	return dataFile + ".manifest.json"
+}
+
+// writeStoreManifest writes the export manifest as JSON
+func writeStoreManifest(file string, manifest *storeExportManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0644)
+}
+
+// readStoreManifest reads an export manifest
+func readStoreManifest(file string) (*storeExportManifest, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &storeExportManifest{}
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func init() {
+	exportStoreCmd.PersistentFlags().String("id", "", "The store ID to export")
+	exportStoreCmd.PersistentFlags().String("root-hash", "", "Export the store at this root hash instead of the current root")
+	exportStoreCmd.PersistentFlags().StringP("output", "o", "", "The file to write the export to (default is <store id>.ndjson)")
+	exportStoreCmd.PersistentFlags().Int("page-size", defaultPageSize, "Maximum size in bytes of each page requested from get_keys_values")
+
+	cobra.CheckErr(viper.BindPFlag("export-store-id", exportStoreCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("export-store-root-hash", exportStoreCmd.PersistentFlags().Lookup("root-hash")))
+	cobra.CheckErr(viper.BindPFlag("export-store-output", exportStoreCmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("export-store-page-size", exportStoreCmd.PersistentFlags().Lookup("page-size")))
+
+	datalayerCmd.AddCommand(exportStoreCmd)
+}
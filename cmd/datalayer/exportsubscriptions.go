@@ -0,0 +1,153 @@
+package datalayer
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	// Pure Go sqlite driver, so reading the data layer database doesn't need cgo in the cross compiled release builds
+	_ "modernc.org/sqlite"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// exportedSubscription is a single subscription in an export file
+type exportedSubscription struct {
+	StoreID  string   `json:"store_id"`
+	URLs     []string `json:"urls"`
+	Owned    bool     `json:"owned"`
+	RootHash string   `json:"root_hash"`
+}
+
+// subscriptionExport is the format of the file written by export-subscriptions and read by import-subscriptions
+type subscriptionExport struct {
+	Subscriptions []exportedSubscription `json:"subscriptions"`
+}
+
+// exportSubscriptionsCmd writes all subscriptions and their URLs to a file
+var exportSubscriptionsCmd = &cobra.Command{
+	Use:   "export-subscriptions",
+	Short: "Exports all datalayer subscriptions with their subscription URLs, owned flag, and current root hash",
+	Example: `chik-tools data export-subscriptions -o subscriptions-export.json
+
+# Then, on the new machine
+chik-tools data import-subscriptions -f subscriptions-export.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		ownedStores, _, err := client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
+		if err != nil {
+			slogs.Logr.Fatal("error getting list of owned data stores", "error", err)
+		}
+
+		subscriptions, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+		if err != nil {
+			slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
+		}
+
+		output := viper.GetString("export-subs-output")
+		if output == "" {
+			// The export is written to stdout, so keep the logs out of the JSON
+			utils.LogToStderr()
+		}
+
+		// The subscriptions RPC only returns store IDs, so the URLs are read from the data layer database
+		// When the database can't be read, for example because the RPC client points at another machine, the
+		// subscriptions are still exported without URLs
+		var urls map[string][]string
+		dbPath, err := dataLayerDatabasePath()
+		if err == nil {
+			urls, err = subscriptionURLs(dbPath)
+		}
+		if err != nil {
+			slogs.Logr.Warn("unable to read subscription URLs from the data layer database. Exporting subscriptions without URLs", "database", dbPath, "error", err)
+		}
+
+		export := subscriptionExport{Subscriptions: []exportedSubscription{}}
+		for _, subscription := range subscriptions.StoreIDs {
+			slogs.Logr.Info("exporting subscription", "store", subscription)
+			exported := exportSubscription(client, subscription, containsStoreID(ownedStores.StoreIDs, subscription), urls[normalizeStoreID(subscription)])
+			export.Subscriptions = append(export.Subscriptions, exported)
+		}
+
+		jsonOutput, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			slogs.Logr.Fatal("error marshaling subscriptions to JSON", "error", err)
+		}
+
+		if output == "" {
+			fmt.Println(string(jsonOutput))
+			return
+		}
+		err = os.WriteFile(output, jsonOutput, 0644)
+		if err != nil {
+			slogs.Logr.Fatal("error writing output file", "error", err)
+		}
+		slogs.Logr.Info("exported subscriptions", "count", len(export.Subscriptions), "output", output)
+	},
+}
+
+// exportSubscription collects the subscription URLs and current root for a single store
+func exportSubscription(client *rpc.Client, storeID string, owned bool, urls []string) exportedSubscription {
+	exported := exportedSubscription{StoreID: storeID, URLs: []string{}, Owned: owned}
+	exported.URLs = append(exported.URLs, urls...)
+
+	root, err := getRoot(client, storeID)
+	if err != nil {
+		// Stores that haven't synced yet don't have a root, which shouldn't prevent exporting the subscription
+		slogs.Logr.Warn("unable to get root hash for store", "store", storeID, "error", err)
+	} else {
+		exported.RootHash = root.Hash
+	}
+
+	return exported
+}
+
+// subscriptionURLs returns the URLs each store was subscribed with, keyed by the normalized store ID
+// URLs data layer added from on chain mirrors are skipped, since data layer adds them again when the store syncs
+func subscriptionURLs(dbPath string) (map[string][]string, error) {
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+
+	rows, err := db.Query("SELECT tree_id, url FROM subscriptions WHERE url IS NOT NULL AND from_wallet == 0 ORDER BY tree_id, url")
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	urls := map[string][]string{}
+	for rows.Next() {
+		var storeID []byte
+		var url string
+		if err = rows.Scan(&storeID, &url); err != nil {
+			return nil, err
+		}
+		id := hex.EncodeToString(storeID)
+		urls[id] = append(urls[id], url)
+	}
+	return urls, rows.Err()
+}
+
+func init() {
+	exportSubscriptionsCmd.PersistentFlags().StringP("output", "o", "", "File to write the export to. Defaults to stdout")
+
+	cobra.CheckErr(viper.BindPFlag("export-subs-output", exportSubscriptionsCmd.PersistentFlags().Lookup("output")))
+
+	datalayerCmd.AddCommand(exportSubscriptionsCmd)
+}
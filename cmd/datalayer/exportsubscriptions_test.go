@@ -0,0 +1,61 @@
+package datalayer
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionURLs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data_layer_testnet11.sqlite")
+	db, err := sql.Open("sqlite", dbPath)
+	assert.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE subscriptions(
+		tree_id BLOB NOT NULL CHECK(length(tree_id) == 32),
+		url TEXT,
+		ignore_till INTEGER,
+		num_consecutive_failures INTEGER,
+		from_wallet tinyint CHECK(from_wallet == 0 OR from_wallet == 1),
+		PRIMARY KEY(tree_id, url)
+	)`)
+	assert.NoError(t, err)
+
+	custom := strings.Repeat("aa", 32)
+	mirrored := strings.Repeat("bb", 32)
+	rows := []struct {
+		storeID    string
+		url        any
+		fromWallet int
+	}{
+		{custom, "https://b.example.com", 0},
+		{custom, "https://a.example.com", 0},
+		{custom, "https://mirror.example.com", 1},
+		{mirrored, "https://mirror.example.com", 1},
+		{mirrored, nil, 0},
+	}
+	for _, row := range rows {
+		_, err = db.Exec("INSERT INTO subscriptions VALUES(unhex(?), ?, 0, 0, ?)", row.storeID, row.url, row.fromWallet)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, db.Close())
+
+	urls, err := subscriptionURLs(dbPath)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		custom: {"https://a.example.com", "https://b.example.com"},
+	}, urls)
+
+	// A missing database, such as when the RPC client points at another machine, is an error the export can skip
+	_, err = subscriptionURLs(filepath.Join(t.TempDir(), "missing.sqlite"))
+	assert.Error(t, err)
+}
+
+func TestContainsStoreID(t *testing.T) {
+	storeID := strings.Repeat("ab", 32)
+	assert.True(t, containsStoreID([]string{storeID}, "0x"+strings.ToUpper(storeID)))
+	assert.True(t, containsStoreID([]string{"0x" + strings.ToUpper(storeID)}, storeID))
+	assert.False(t, containsStoreID([]string{storeID}, strings.Repeat("cd", 32)))
+}
@@ -0,0 +1,77 @@
+package datalayer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// feeAuto is the --fee value that uses the full node fee estimate
+const feeAuto = "auto"
+
+// mirrorSpendCost is a rough upper bound on the CLVM cost of a single mirror create or delete transaction
+const mirrorSpendCost = 20000000
+
+// feeEstimateOptions is the request for get_fee_estimate
+type feeEstimateOptions struct {
+	TargetTimes []uint64 `json:"target_times"`
+	Cost        uint64   `json:"cost"`
+}
+
+// feeEstimateResponse is the response from get_fee_estimate
+type feeEstimateResponse struct {
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	Estimates   []float64 `json:"estimates"`
+	TargetTimes []uint64  `json:"target_times"`
+}
+
+// addFeeFlags adds the --fee and --fee-target-time flags shared by commands that send datalayer transactions
+func addFeeFlags(cmd *cobra.Command, usage string) {
+	cmd.PersistentFlags().StringP("fee", "m", "0", usage+". Amounts are XCK unless they end in mojos, or use auto to get an estimate from the full node")
+	cmd.PersistentFlags().Uint64("fee-target-time", 60, "Target confirmation time in seconds when using --fee auto")
+}
+
+// resolveFee returns the fee in mojos for a single transaction
+// When the fee is "auto", the full node is asked for a fee estimate for the target confirmation time
+func resolveFee(client *rpc.Client, fee string, targetTime uint64) (uint64, error) {
+	if !strings.EqualFold(strings.TrimSpace(fee), feeAuto) {
+		return utils.ParseAmount(fee, utils.UnitXCK)
+	}
+
+	req, err := client.FullNodeService.NewRequest("get_fee_estimate", &feeEstimateOptions{
+		TargetTimes: []uint64{targetTime},
+		Cost:        mirrorSpendCost,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error creating fee estimate request: %w", err)
+	}
+	resp := &feeEstimateResponse{}
+	_, err = client.FullNodeService.Do(req, resp)
+	if err != nil {
+		return 0, fmt.Errorf("error getting fee estimate from full node: %w", err)
+	}
+	if !resp.Success || len(resp.Estimates) == 0 {
+		return 0, fmt.Errorf("full node did not return a fee estimate: %s", resp.Error)
+	}
+
+	feeMojos := uint64(resp.Estimates[0])
+	slogs.Logr.Info("estimated fee from full node", "target_seconds", targetTime, "xck", utils.FormatXCK(feeMojos), "mojos", feeMojos)
+	return feeMojos, nil
+}
+
+// logFeeBudget shows the total fees and mirror coin amounts for all transactions before any are sent
+func logFeeBudget(transactions int, feeMojos uint64, mirrorMojos uint64) {
+	totalFees := uint64(transactions) * feeMojos
+	slogs.Logr.Info("fee budget",
+		"transactions", transactions,
+		"fee_per_transaction_xck", utils.FormatXCK(feeMojos),
+		"total_fees_xck", utils.FormatXCK(totalFees),
+		"mirror_amounts_xck", utils.FormatXCK(mirrorMojos),
+		"total_xck", utils.FormatXCK(totalFees+mirrorMojos))
+}
@@ -6,9 +6,12 @@ import (
 	"time"
 
 	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
 	"github.com/chik-network/go-modules/pkg/slogs"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
 )
 
 // fixMirrorsCmd Replaces one mirror url with another for all mirrors with the url
@@ -17,6 +20,9 @@ var fixMirrorsCmd = &cobra.Command{
 	Short: "For all owned mirrors, replaces one url with a new url",
 	Example: `chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m 0.00000001
 
+# Use the full node fee estimate for confirmation within 2 minutes
+chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m auto --fee-target-time 120
+
 # Show what changes would be made without actually fixing mirrors
 chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m 0.00000001 --dry-run`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -36,14 +42,20 @@ chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m 0
 		}
 
 		// Figure out what fee we are using
-		feeXCK := viper.GetFloat64("fix-mirror-fee")
-		feeMojos := uint64(feeXCK * 1000000000000)
+		feeMojos, err := resolveFee(client, viper.GetString("fix-mirror-fee"), viper.GetUint64("fix-mirror-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+		mirrorAmount, err := utils.ParseAmount(viper.GetString("fix-mirror-amount"), utils.UnitMojo)
+		if err != nil {
+			slogs.Logr.Fatal("invalid mirror amount", "error", err)
+		}
 		dryRun := viper.GetBool("dry-run")
 
 		if dryRun {
-			slogs.Logr.Info("DRY RUN: Would use fee for all transactions", "xck", feeXCK, "mojos", feeMojos)
+			slogs.Logr.Info("DRY RUN: Would use fee for all transactions", "xck", utils.FormatXCK(feeMojos), "mojos", feeMojos)
 		} else {
-			slogs.Logr.Info("fee for all transactions", "xck", feeXCK, "mojos", feeMojos)
+			slogs.Logr.Info("fee for all transactions", "xck", utils.FormatXCK(feeMojos), "mojos", feeMojos)
 		}
 
 		subscriptions, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
@@ -51,9 +63,11 @@ chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m 0
 			slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
 		}
 
+		// Find every mirror to replace first, so the full fee budget can be shown before spending anything
+		toDelete := map[string][]types.DatalayerMirror{}
+		transactions := 0
+		replacements := 0
 		for _, sub := range subscriptions.StoreIDs {
-			foundAnyMirror := false
-
 			mirrors, _, err := client.DataLayerService.GetMirrors(&rpc.DatalayerGetMirrorsOptions{
 				ID: sub,
 			})
@@ -66,51 +80,63 @@ chik-tools data fix-mirrors -b 127.0.0.1 -n https://my-dl-domain.com -a 300 -m 0
 				}
 				for _, url := range mirror.URLs {
 					if strings.EqualFold(url, viper.GetString("fix-mirror-bad-url")) {
-						foundAnyMirror = true
-						if dryRun {
-							slogs.Logr.Info("DRY RUN: Would delete mirror", "store", sub, "mirror", mirror.CoinID.String())
-							continue
-						}
-
-						waitForAvailableBalance(client, feeMojos)
-						slogs.Logr.Info("deleting mirror", "store", sub, "mirror", mirror.CoinID.String())
-						_, _, err := client.DataLayerService.DeleteMirror(&rpc.DatalayerDeleteMirrorOptions{
-							CoinID: mirror.CoinID.String(),
-							Fee:    viper.GetUint64("fix-mirror-fee"),
-						})
-						if err != nil {
-							slogs.Logr.Fatal("error deleting mirror", "store", sub, "mirror", mirror.CoinID.String(), "error", err)
-						}
+						toDelete[sub] = append(toDelete[sub], mirror)
+						transactions++
 						break
 					}
 				}
 			}
+			// In case there's a weird edge case where we have multiple mirrors on the same bad
+			// url, we consolidate down to just one replacement
+			if len(toDelete[sub]) > 0 {
+				transactions++
+				replacements++
+			}
+		}
+		logFeeBudget(transactions, feeMojos, uint64(replacements)*mirrorAmount)
+
+		for _, sub := range subscriptions.StoreIDs {
+			if len(toDelete[sub]) == 0 {
+				continue
+			}
 
-			// Outside the mirror loop, in case there's a weird edge case where we have multiple mirrors on the same bad
-			// url, we consolidate down to just one
-			if foundAnyMirror {
-				mirrorAmount := viper.GetUint64("fix-mirror-amount")
+			for _, mirror := range toDelete[sub] {
 				if dryRun {
-					slogs.Logr.Info("DRY RUN: Would add replacement mirror",
-						"store", sub,
-						"url", viper.GetString("fix-mirror-new-url"),
-						"amount", mirrorAmount,
-						"fee", feeMojos)
+					slogs.Logr.Info("DRY RUN: Would delete mirror", "store", sub, "mirror", mirror.CoinID.String())
 					continue
 				}
 
-				waitForAvailableBalance(client, mirrorAmount+feeMojos)
-				slogs.Logr.Info("adding replacement mirror", "store", sub)
-				_, _, err = client.DataLayerService.AddMirror(&rpc.DatalayerAddMirrorOptions{
-					ID:     sub,
-					URLs:   []string{viper.GetString("fix-mirror-new-url")},
-					Amount: mirrorAmount,
+				waitForAvailableBalance(client, feeMojos)
+				slogs.Logr.Info("deleting mirror", "store", sub, "mirror", mirror.CoinID.String())
+				_, _, err := client.DataLayerService.DeleteMirror(&rpc.DatalayerDeleteMirrorOptions{
+					CoinID: mirror.CoinID.String(),
 					Fee:    feeMojos,
 				})
 				if err != nil {
-					slogs.Logr.Fatal("error adding new mirror", "store", sub, "error", err)
+					slogs.Logr.Fatal("error deleting mirror", "store", sub, "mirror", mirror.CoinID.String(), "error", err)
 				}
 			}
+
+			if dryRun {
+				slogs.Logr.Info("DRY RUN: Would add replacement mirror",
+					"store", sub,
+					"url", viper.GetString("fix-mirror-new-url"),
+					"amount", mirrorAmount,
+					"fee", feeMojos)
+				continue
+			}
+
+			waitForAvailableBalance(client, mirrorAmount+feeMojos)
+			slogs.Logr.Info("adding replacement mirror", "store", sub)
+			_, _, err = client.DataLayerService.AddMirror(&rpc.DatalayerAddMirrorOptions{
+				ID:     sub,
+				URLs:   []string{viper.GetString("fix-mirror-new-url")},
+				Amount: mirrorAmount,
+				Fee:    feeMojos,
+			})
+			if err != nil {
+				slogs.Logr.Fatal("error adding new mirror", "store", sub, "error", err)
+			}
 		}
 
 		if dryRun {
@@ -149,12 +175,13 @@ func waitForAvailableBalance(client *rpc.Client, amount uint64) {
 }
 
 func init() {
-	fixMirrorsCmd.PersistentFlags().Float64P("fee", "m", 0, "Fee to use when deleting and launching the mirrors. The fee is used per mirror. Units are XCK")
+	addFeeFlags(fixMirrorsCmd, "Fee to use when deleting and launching the mirrors. The fee is used per mirror")
 	fixMirrorsCmd.PersistentFlags().StringP("new-url", "n", "", "New mirror URL (required)")
 	fixMirrorsCmd.PersistentFlags().StringP("bad-url", "b", "", "Old mirror URL to replace (required)")
-	fixMirrorsCmd.PersistentFlags().Uint64P("amount", "a", 100, "Mirror coin amount in mojos")
+	fixMirrorsCmd.PersistentFlags().StringP("amount", "a", "100", "Mirror coin amount. Amounts are mojos unless they end in xck")
 
 	cobra.CheckErr(viper.BindPFlag("fix-mirror-fee", fixMirrorsCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("fix-mirror-fee-target-time", fixMirrorsCmd.PersistentFlags().Lookup("fee-target-time")))
 	cobra.CheckErr(viper.BindPFlag("fix-mirror-new-url", fixMirrorsCmd.PersistentFlags().Lookup("new-url")))
 	cobra.CheckErr(viper.BindPFlag("fix-mirror-bad-url", fixMirrorsCmd.PersistentFlags().Lookup("bad-url")))
 	cobra.CheckErr(viper.BindPFlag("fix-mirror-amount", fixMirrorsCmd.PersistentFlags().Lookup("amount")))
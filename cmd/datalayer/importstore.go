@@ -0,0 +1,446 @@
+package datalayer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// defaultMaxBatchBytes is the default limit for the size of the keys and values in a single batch_update
+const defaultMaxBatchBytes = 1024 * 1024
+
+// defaultConfirmTimeout is how long to wait for a data layer transaction to confirm by default
+const defaultConfirmTimeout = time.Hour
+
+// changelistEntryOverhead approximates the JSON encoding overhead of a changelist entry beyond the key and value
+const changelistEntryOverhead = 48
+
+// importStoreCmd loads a store export into a new or existing store
+var importStoreCmd = &cobra.Command{
+	Use:   "import-store",
+	Short: "Imports a file written by export-store into a new or existing store",
+	Example: `# Create a new store and load the export into it
+chik-tools data import-store --file abcd1234.ndjson -m 0.00001
+
+# Load the export into an existing owned store, in batches of at most 512KiB
+chik-tools data import-store --file abcd1234.ndjson --id ef567890 --max-batch-bytes 524288
+
+# Resume an interrupted import from the last confirmed batch by running the same command again
+chik-tools data import-store --file abcd1234.ndjson -m 0.00001
+
+# Show what would be imported without making any changes
+chik-tools data import-store --file abcd1234.ndjson --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		dataFile := viper.GetString("import-store-file")
+		if dataFile == "" {
+			slogs.Logr.Fatal("export file is required")
+		}
+		err = verifyStoreExport(dataFile)
+		if err != nil {
+			if !viper.GetBool("import-store-skip-verify") {
+				slogs.Logr.Fatal("export file failed verification. Use --skip-verify to import anyway", "error", err)
+			}
+			slogs.Logr.Warn("export file failed verification", "error", err)
+		}
+
+		maxBatchBytes := viper.GetInt("import-store-max-batch-bytes")
+		batches, keys, err := countImportBatches(dataFile, maxBatchBytes)
+		if err != nil {
+			slogs.Logr.Fatal("error reading export file", "error", err)
+		}
+
+		progressFile := viper.GetString("import-store-progress")
+		if progressFile == "" {
+			progressFile = dataFile + ".progress.json"
+		}
+		progress, err := loadImportProgress(progressFile)
+		if err != nil {
+			slogs.Logr.Fatal("error reading import progress file", "file", progressFile, "error", err)
+		}
+		storeID := viper.GetString("import-store-id")
+		if progress != nil {
+			if storeID != "" && normalizeStoreID(storeID) != normalizeStoreID(progress.StoreID) {
+				slogs.Logr.Fatal("the progress file is for an import into a different store. Remove it to start over", "file", progressFile, "store", progress.StoreID)
+			}
+			if progress.MaxBatchBytes != maxBatchBytes {
+				slogs.Logr.Fatal("the interrupted import used a different batch size. Use the same --max-batch-bytes to resume", "file", progressFile, "max_batch_bytes", progress.MaxBatchBytes)
+			}
+			storeID = progress.StoreID
+			slogs.Logr.Info("Resuming interrupted import", "store", storeID, "confirmed_batches", progress.Batches, "of", batches)
+		}
+
+		feeMojos, err := resolveFee(client, viper.GetString("import-store-fee"), viper.GetUint64("import-store-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+
+		remaining := batches
+		if progress != nil {
+			remaining -= progress.Batches
+		}
+		transactions := remaining
+		if storeID == "" {
+			transactions++
+		}
+		logFeeBudget(transactions, feeMojos, 0)
+
+		if viper.GetBool("dry-run") {
+			if storeID == "" {
+				slogs.Logr.Info("DRY RUN: Would create a new store")
+			}
+			slogs.Logr.Info("DRY RUN: Would import keys", "keys", keys, "batches", remaining, "store", storeID)
+			slogs.Logr.Info("DRY RUN: No changes were made")
+			return
+		}
+
+		target := storeID
+		if target == "" {
+			target = "a new store"
+		}
+		if !utils.ConfirmAction(fmt.Sprintf("Import %d keys into %s in %d batches? (y/N)", keys, target, remaining), viper.GetBool("import-store-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		timeout := viper.GetDuration("import-store-confirm-timeout")
+		if progress == nil {
+			progress = &importProgress{StoreID: storeID, MaxBatchBytes: maxBatchBytes}
+			if storeID == "" {
+				waitForAvailableBalance(client, feeMojos)
+				storeID, err = createDataStore(client, feeMojos)
+				if err != nil {
+					slogs.Logr.Fatal("error creating data store", "error", err)
+				}
+				progress.StoreID = storeID
+				progress.Submitted = true
+				slogs.Logr.Info("Created new store. Waiting for it to confirm", "store", storeID)
+			} else {
+				root, err := getRoot(client, storeID)
+				if err != nil {
+					slogs.Logr.Fatal("error getting current root for store", "store", storeID, "error", err)
+				}
+				progress.RootHash = root.Hash
+			}
+			saveImportProgressOrExit(progressFile, progress)
+		}
+		if progress.Submitted {
+			err = confirmSubmittedImport(client, progress, timeout)
+			if err != nil {
+				slogs.Logr.Fatal("error waiting for the last submitted transaction to confirm. Run the command again to resume", "store", storeID, "error", err)
+			}
+			saveImportProgressOrExit(progressFile, progress)
+		}
+
+		batch := 0
+		err = readImportBatches(dataFile, maxBatchBytes, func(changelist []changelistEntry) error {
+			batch++
+			if batch <= progress.Batches {
+				return nil
+			}
+			slogs.Logr.Info("Submitting batch", "store", storeID, "batch", batch, "of", batches, "changes", len(changelist))
+			// Recorded before submitting, so a resumed run checks the chain instead of sending the batch twice
+			progress.Submitted = true
+			saveImportProgressOrExit(progressFile, progress)
+			rootHash, err := submitBatch(client, storeID, changelist, feeMojos, progress.RootHash, timeout)
+			if err != nil {
+				return fmt.Errorf("batch %d: %w", batch, err)
+			}
+			progress.Batches = batch
+			progress.RootHash = rootHash
+			progress.Submitted = false
+			saveImportProgressOrExit(progressFile, progress)
+			slogs.Logr.Info("Batch confirmed", "store", storeID, "batch", batch, "root_hash", rootHash)
+			return nil
+		})
+		if err != nil {
+			slogs.Logr.Fatal("error importing store. Run the command again to resume from the last confirmed batch", "store", storeID, "error", err)
+		}
+
+		if err = os.Remove(progressFile); err != nil {
+			slogs.Logr.Warn("error removing import progress file", "file", progressFile, "error", err)
+		}
+		slogs.Logr.Info("Imported store", "store", storeID, "keys", keys, "root_hash", progress.RootHash)
+	},
+}
+
+// importProgress records how far an import got, so an interrupted import resumes from the last confirmed batch
+type importProgress struct {
+	StoreID       string `json:"store_id"`
+	MaxBatchBytes int    `json:"max_batch_bytes"`
+	// Batches is the number of batches that are confirmed on chain
+	Batches int `json:"batches"`
+	// RootHash is the root of the store after the confirmed batches
+	RootHash string `json:"root_hash"`
+	// Submitted is set while the store creation or the next batch may have been sent but is not confirmed yet
+	Submitted bool `json:"submitted"`
+}
+
+// loadImportProgress reads the progress file. A missing file returns nil, since there is nothing to resume
+func loadImportProgress(file string) (*importProgress, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	progress := &importProgress{}
+	err = json.Unmarshal(content, progress)
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// saveImportProgress writes the progress to a temporary file and renames it, so a crash never leaves a partial file
+func saveImportProgress(file string, progress *importProgress) error {
+	content, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpFile := file + ".tmp"
+	err = os.WriteFile(tmpFile, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+// saveImportProgressOrExit saves the progress, exiting when it can't be saved since the import could not be resumed safely
+func saveImportProgressOrExit(file string, progress *importProgress) {
+	if err := saveImportProgress(file, progress); err != nil {
+		slogs.Logr.Fatal("error writing import progress file", "file", file, "error", err)
+	}
+}
+
+// confirmSubmittedImport resolves a transaction that an interrupted import sent, but didn't see confirm
+// When the store still has the previous confirmed root, the transaction never made it and the batch is sent again
+func confirmSubmittedImport(client *rpc.Client, progress *importProgress, timeout time.Duration) error {
+	creatingStore := progress.RootHash == ""
+	if !creatingStore {
+		root, err := getRoot(client, progress.StoreID)
+		if err != nil {
+			return err
+		}
+		if root.Confirmed && strings.EqualFold(root.Hash, progress.RootHash) {
+			slogs.Logr.Info("The last batch of the interrupted import was not confirmed. Submitting it again", "store", progress.StoreID, "batch", progress.Batches+1)
+			progress.Submitted = false
+			return nil
+		}
+	}
+
+	rootHash, err := waitForRootConfirmed(client, progress.StoreID, progress.RootHash, timeout)
+	if err != nil {
+		return err
+	}
+	if !creatingStore {
+		progress.Batches++
+	}
+	progress.RootHash = rootHash
+	progress.Submitted = false
+	return nil
+}
+
+// verifyStoreExport checks the data file against its manifest, if there is one
+func verifyStoreExport(dataFile string) error {
+	manifest, err := readStoreManifest(manifestPath(dataFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			slogs.Logr.Warn("no manifest found for export file. Skipping verification", "file", dataFile)
+			return nil
+		}
+		return fmt.Errorf("error reading manifest: %w", err)
+	}
+	if manifest.Version > storeExportVersion {
+		return fmt.Errorf("export version %d is newer than this tool supports", manifest.Version)
+	}
+
+	f, err := os.Open(dataFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if size != manifest.Bytes || hex.EncodeToString(hash.Sum(nil)) != manifest.SHA256 {
+		return fmt.Errorf("checksum of %s does not match the manifest", dataFile)
+	}
+
+	slogs.Logr.Info("Verified export file", "store", manifest.StoreID, "root_hash", manifest.RootHash, "keys", manifest.KeyCount)
+	return nil
+}
+
+// countImportBatches returns the number of batches and keys the export file will be imported in
+func countImportBatches(dataFile string, maxBatchBytes int) (int, int, error) {
+	batches, keys := 0, 0
+	err := readImportBatches(dataFile, maxBatchBytes, func(changelist []changelistEntry) error {
+		batches++
+		keys += len(changelist)
+		return nil
+	})
+	return batches, keys, err
+}
+
+// readImportBatches reads the export file and calls fn with upsert changelists no larger than maxBatchBytes
+func readImportBatches(dataFile string, maxBatchBytes int, fn func([]changelistEntry) error) error {
+	f, err := os.Open(dataFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	batcher := &changelistBatcher{maxBytes: maxBatchBytes}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchBytes*2+changelistEntryOverhead)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		kv := exportedKeyValue{}
+		err = json.Unmarshal(scanner.Bytes(), &kv)
+		if err != nil {
+			return fmt.Errorf("invalid entry on line %d: %w", line, err)
+		}
+		ready, err := batcher.add(changelistEntry{
+			Action: changeUpsert,
+			Key:    strings.TrimPrefix(kv.Key, "0x"),
+			Value:  strings.TrimPrefix(kv.Value, "0x"),
+		})
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if ready != nil {
+			if err = fn(ready); err != nil {
+				return err
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if remaining := batcher.flush(); remaining != nil {
+		return fn(remaining)
+	}
+	return nil
+}
+
+// changelistBatcher groups changes into changelists that stay under a size limit
+type changelistBatcher struct {
+	maxBytes int
+	size     int
+	pending  []changelistEntry
+}
+
+// add adds the change to the current changelist
+// When the change doesn't fit, the full changelist is returned and the change starts the next one
+func (b *changelistBatcher) add(entry changelistEntry) ([]changelistEntry, error) {
+	// Keys and values are hex, so they are counted as the bytes they decode to
+	size := len(entry.Key)/2 + len(entry.Value)/2 + changelistEntryOverhead
+	if size > b.maxBytes {
+		return nil, fmt.Errorf("change for key %s is %d bytes, which is larger than the batch limit of %d bytes", entry.Key, size, b.maxBytes)
+	}
+
+	var ready []changelistEntry
+	if b.size+size > b.maxBytes {
+		ready = b.flush()
+	}
+	b.pending = append(b.pending, entry)
+	b.size += size
+	return ready, nil
+}
+
+// flush returns the current changelist, if it has any changes, and starts a new one
+func (b *changelistBatcher) flush() []changelistEntry {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	ready := b.pending
+	b.pending = nil
+	b.size = 0
+	return ready
+}
+
+// submitBatch sends the changelist once the wallet can pay the fee, then waits for the new root to confirm so the
+// next batch builds on it. The new root hash is returned
+func submitBatch(client *rpc.Client, storeID string, changelist []changelistEntry, feeMojos uint64, previousRoot string, timeout time.Duration) (string, error) {
+	waitForAvailableBalance(client, feeMojos)
+	_, err := batchUpdate(client, storeID, changelist, feeMojos)
+	if err != nil {
+		return "", err
+	}
+	return waitForRootConfirmed(client, storeID, previousRoot, timeout)
+}
+
+// waitForRootConfirmed blocks execution until the store has a confirmed root that is different from previousRoot
+// and returns the new root hash. A timeout of 0 waits forever
+func waitForRootConfirmed(client *rpc.Client, storeID string, previousRoot string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if timeout > 0 && time.Now().After(deadline) {
+			return "", fmt.Errorf("no new root confirmed within %s. The transaction may still be pending, or may have been dropped", timeout)
+		}
+
+		root, err := getRoot(client, storeID)
+		if err != nil {
+			slogs.Logr.Warn("error checking store root. Retrying in 5 seconds", "store", storeID, "error", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		if !root.Confirmed || strings.EqualFold(root.Hash, previousRoot) {
+			slogs.Logr.Debug("waiting for new root to confirm", "store", storeID, "root_hash", root.Hash, "confirmed", root.Confirmed)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		return root.Hash, nil
+	}
+}
+
+func init() {
+	importStoreCmd.PersistentFlags().StringP("file", "f", "", "The export file written by export-store (required)")
+	importStoreCmd.PersistentFlags().String("id", "", "Import into this existing owned store instead of creating a new store")
+	importStoreCmd.PersistentFlags().Int("max-batch-bytes", defaultMaxBatchBytes, "Maximum size in bytes of the decoded keys and values in each batch_update")
+	importStoreCmd.PersistentFlags().Duration("confirm-timeout", defaultConfirmTimeout, "How long to wait for the store and each batch to confirm before giving up. 0 waits forever")
+	importStoreCmd.PersistentFlags().String("progress", "", "File that records the confirmed batches, so an interrupted import can resume (default is the export file with .progress.json appended)")
+	importStoreCmd.PersistentFlags().Bool("skip-verify", false, "Import even if the file doesn't match its manifest")
+	importStoreCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+	addFeeFlags(importStoreCmd, "Fee to use for creating the store and for each batch")
+
+	cobra.CheckErr(viper.BindPFlag("import-store-file", importStoreCmd.PersistentFlags().Lookup("file")))
+	cobra.CheckErr(viper.BindPFlag("import-store-id", importStoreCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("import-store-max-batch-bytes", importStoreCmd.PersistentFlags().Lookup("max-batch-bytes")))
+	cobra.CheckErr(viper.BindPFlag("import-store-confirm-timeout", importStoreCmd.PersistentFlags().Lookup("confirm-timeout")))
+	cobra.CheckErr(viper.BindPFlag("import-store-progress", importStoreCmd.PersistentFlags().Lookup("progress")))
+	cobra.CheckErr(viper.BindPFlag("import-store-skip-verify", importStoreCmd.PersistentFlags().Lookup("skip-verify")))
+	cobra.CheckErr(viper.BindPFlag("import-store-yes", importStoreCmd.PersistentFlags().Lookup("yes")))
+	cobra.CheckErr(viper.BindPFlag("import-store-fee", importStoreCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("import-store-fee-target-time", importStoreCmd.PersistentFlags().Lookup("fee-target-time")))
+
+	datalayerCmd.AddCommand(importStoreCmd)
+}
@@ -0,0 +1,47 @@
+package datalayer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadImportBatches(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "store.ndjson")
+	err := os.WriteFile(dataFile, []byte(`{"key":"0x01","value":"0xaaaa"}
+{"key":"0x02","value":"0xbbbb"}
+
+{"key":"0x03","value":"0xcccc"}
+`), 0644)
+	assert.NoError(t, err)
+
+	// Each change is 3 bytes once decoded from hex, plus the overhead, so two fit in a batch
+	var batches [][]changelistEntry
+	err = readImportBatches(dataFile, 2*(3+changelistEntryOverhead), func(changelist []changelistEntry) error {
+		batches = append(batches, changelist)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]changelistEntry{
+		{{Action: changeUpsert, Key: "01", Value: "aaaa"}, {Action: changeUpsert, Key: "02", Value: "bbbb"}},
+		{{Action: changeUpsert, Key: "03", Value: "cccc"}},
+	}, batches)
+
+	err = readImportBatches(dataFile, 10, func(changelist []changelistEntry) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestImportProgress(t *testing.T) {
+	progressFile := filepath.Join(t.TempDir(), "store.ndjson.progress.json")
+	progress, err := loadImportProgress(progressFile)
+	assert.NoError(t, err)
+	assert.Nil(t, progress)
+
+	saved := &importProgress{StoreID: "abcd", MaxBatchBytes: 1024, Batches: 3, RootHash: "0x1234", Submitted: true}
+	assert.NoError(t, saveImportProgress(progressFile, saved))
+	progress, err = loadImportProgress(progressFile)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, progress)
+}
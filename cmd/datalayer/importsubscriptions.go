@@ -0,0 +1,80 @@
+package datalayer
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// importSubscriptionsCmd subscribes to all stores in a file written by export-subscriptions
+var importSubscriptionsCmd = &cobra.Command{
+	Use:   "import-subscriptions",
+	Short: "Subscribes to all stores from an export-subscriptions file, using the exported URLs",
+	Example: `chik-tools data import-subscriptions -f subscriptions-export.json
+
+# Show what changes would be made without actually subscribing
+chik-tools data import-subscriptions -f subscriptions-export.json --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		content, err := os.ReadFile(viper.GetString("import-subs-file"))
+		if err != nil {
+			slogs.Logr.Fatal("Unable to read input file", "error", err)
+		}
+		export := &subscriptionExport{}
+		err = json.Unmarshal(content, export)
+		if err != nil {
+			slogs.Logr.Fatal("Could not parse the subscriptions export file", "error", err)
+		}
+
+		existing, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+		if err != nil {
+			slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
+		}
+
+		dryRun := viper.GetBool("dry-run")
+		for _, sub := range export.Subscriptions {
+			if containsStoreID(existing.StoreIDs, sub.StoreID) {
+				slogs.Logr.Info("Already subscribed to store, skipping", "store", sub.StoreID)
+				continue
+			}
+			if dryRun {
+				slogs.Logr.Info("DRY RUN: Would subscribe to store", "store", sub.StoreID, "urls", sub.URLs)
+				continue
+			}
+
+			slogs.Logr.Info("Subscribing to store", "store", sub.StoreID, "urls", sub.URLs)
+			resp, _, err := client.DataLayerService.Subscribe(&rpc.DatalayerSubscribeOptions{
+				ID:   sub.StoreID,
+				URLs: sub.URLs,
+			})
+			if err != nil {
+				slogs.Logr.Error("Error subscribing to datastore", "store", sub.StoreID, "error", err)
+				continue
+			}
+			if !resp.Success {
+				slogs.Logr.Error("unknown error when subscribing to datastore", "store", sub.StoreID)
+			}
+		}
+
+		if dryRun {
+			slogs.Logr.Info("DRY RUN: No changes were made to subscriptions")
+		}
+	},
+}
+
+func init() {
+	importSubscriptionsCmd.PersistentFlags().StringP("file", "f", "", "The file written by export-subscriptions")
+
+	cobra.CheckErr(importSubscriptionsCmd.MarkPersistentFlagRequired("file"))
+	cobra.CheckErr(viper.BindPFlag("import-subs-file", importSubscriptionsCmd.PersistentFlags().Lookup("file")))
+
+	datalayerCmd.AddCommand(importSubscriptionsCmd)
+}
@@ -0,0 +1,258 @@
+package datalayer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// allOwnedStores is the key in the desired state file that applies to every owned store without its own entry
+const allOwnedStores = "all-owned"
+
+// defaultMirrorAmount is the mirror coin amount used when the desired state doesn't set one
+const defaultMirrorAmount = "100"
+
+// Actions in a mirror plan
+const (
+	mirrorActionAdd    = "add"
+	mirrorActionDelete = "delete"
+)
+
+// mirrorState is the desired state file format
+type mirrorState struct {
+	Stores map[string][]desiredMirror `yaml:"stores"`
+}
+
+// desiredMirror is a single mirror that should exist for a store
+// Amount is mojos unless it ends in xck
+type desiredMirror struct {
+	URLs   []string `yaml:"urls"`
+	Amount string   `yaml:"amount"`
+}
+
+// mirrorAction is a single add or delete needed to reach the desired state
+type mirrorAction struct {
+	Action  string   `json:"action"`
+	StoreID string   `json:"store_id"`
+	CoinID  string   `json:"coin_id,omitempty"`
+	URLs    []string `json:"urls"`
+	Amount  uint64   `json:"amount"`
+}
+
+// key identifies the action in the progress journal. Identical mirrors share a key, and the journal counts them
+func (a mirrorAction) key() string {
+	if a.Action == mirrorActionDelete {
+		return fmt.Sprintf("%s:%s", a.Action, a.CoinID)
+	}
+	return fmt.Sprintf("%s:%s:%s:%d", a.Action, a.StoreID, strings.Join(normalizeURLs(a.URLs), ","), a.Amount)
+}
+
+// mirrorsCmd represents the mirrors command
+var mirrorsCmd = &cobra.Command{
+	Use:   "mirrors",
+	Short: "Reconcile owned mirrors with a desired state file",
+	Long: `Reconcile owned mirrors with a desired state file.
+
+The desired state file maps store IDs to the mirrors that should exist for them. The
+special ID all-owned applies to every owned store that doesn't have its own entry.
+Amounts are mojos unless they end in xck. Only mirrors owned by this wallet are changed.
+
+stores:
+  all-owned:
+    - urls: ["https://dl.example.com"]
+      amount: 100
+  abcd1234:
+    - urls: ["https://dl.example.com", "https://dl2.example.com"]
+      amount: 0.000001xck`,
+}
+
+// loadMirrorState reads the desired state file
+func loadMirrorState(file string) (*mirrorState, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	state := &mirrorState{}
+	err = yaml.Unmarshal(content, state)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing desired state file: %w", err)
+	}
+	return state, nil
+}
+
+// planMirrors compares the desired state with the current mirrors and returns the actions needed
+func planMirrors(client *rpc.Client, state *mirrorState) ([]mirrorAction, error) {
+	var ownedStoreIDs []string
+	if _, ok := state.Stores[allOwnedStores]; ok {
+		owned, _, err := client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting list of owned data stores: %w", err)
+		}
+		ownedStoreIDs = owned.StoreIDs
+	}
+	targets, err := mirrorTargets(state, ownedStoreIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	storeIDs := make([]string, 0, len(targets))
+	for storeID := range targets {
+		storeIDs = append(storeIDs, storeID)
+	}
+	sort.Strings(storeIDs)
+
+	var actions []mirrorAction
+	for _, storeID := range storeIDs {
+		mirrors, _, err := client.DataLayerService.GetMirrors(&rpc.DatalayerGetMirrorsOptions{
+			ID: storeID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching mirrors for store %s: %w", storeID, err)
+		}
+		storeActions, err := diffMirrors(storeID, targets[storeID], mirrors.Mirrors)
+		if err != nil {
+			return nil, fmt.Errorf("invalid desired state for store %s: %w", storeID, err)
+		}
+		actions = append(actions, storeActions...)
+	}
+
+	return actions, nil
+}
+
+// mirrorTargets returns the desired mirrors for each store, keyed by the normalized store ID
+// Entries for a specific store override all-owned for that store, however the store ID is written
+func mirrorTargets(state *mirrorState, ownedStoreIDs []string) (map[string][]desiredMirror, error) {
+	targets := map[string][]desiredMirror{}
+	if desired, ok := state.Stores[allOwnedStores]; ok {
+		for _, storeID := range ownedStoreIDs {
+			targets[normalizeStoreID(storeID)] = desired
+		}
+	}
+
+	entries := map[string]string{}
+	for storeID, desired := range state.Stores {
+		if storeID == allOwnedStores {
+			continue
+		}
+		normalized := normalizeStoreID(storeID)
+		if previous, ok := entries[normalized]; ok {
+			names := []string{previous, storeID}
+			sort.Strings(names)
+			return nil, fmt.Errorf("the desired state file lists store %s more than once, as %s and %s", normalized, names[0], names[1])
+		}
+		entries[normalized] = storeID
+		targets[normalized] = desired
+	}
+	return targets, nil
+}
+
+// diffMirrors returns the adds and deletes that turn the owned mirrors for a store into the desired mirrors
+// A mirror matches when it has the same set of URLs and the same amount
+func diffMirrors(storeID string, desired []desiredMirror, current []types.DatalayerMirror) ([]mirrorAction, error) {
+	var owned []types.DatalayerMirror
+	for _, mirror := range current {
+		if mirror.Ours {
+			owned = append(owned, mirror)
+		}
+	}
+	matched := make([]bool, len(owned))
+
+	var actions []mirrorAction
+	for _, want := range desired {
+		if len(want.URLs) == 0 {
+			return nil, fmt.Errorf("mirrors must have at least one URL")
+		}
+		amountStr := want.Amount
+		if amountStr == "" {
+			amountStr = defaultMirrorAmount
+		}
+		amount, err := utils.ParseAmount(amountStr, utils.UnitMojo)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for idx, mirror := range owned {
+			if matched[idx] || mirror.Amount != amount || !slices.Equal(normalizeURLs(mirror.URLs), normalizeURLs(want.URLs)) {
+				continue
+			}
+			matched[idx] = true
+			found = true
+			break
+		}
+		if !found {
+			actions = append(actions, mirrorAction{
+				Action:  mirrorActionAdd,
+				StoreID: storeID,
+				URLs:    want.URLs,
+				Amount:  amount,
+			})
+		}
+	}
+
+	for idx, mirror := range owned {
+		if matched[idx] {
+			continue
+		}
+		actions = append(actions, mirrorAction{
+			Action:  mirrorActionDelete,
+			StoreID: storeID,
+			CoinID:  mirror.CoinID.String(),
+			URLs:    mirror.URLs,
+			Amount:  mirror.Amount,
+		})
+	}
+
+	return actions, nil
+}
+
+// normalizeURLs returns a sorted, lowercase copy of the URLs so they can be compared as a set
+func normalizeURLs(urls []string) []string {
+	normalized := make([]string, 0, len(urls))
+	for _, url := range urls {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(strings.TrimSpace(url)), "/"))
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// printMirrorPlan writes the actions as a table
+func printMirrorPlan(out io.Writer, actions []mirrorAction) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACTION\tSTORE\tCOIN\tAMOUNT\tURLS")
+	adds, deletes := 0, 0
+	for _, action := range actions {
+		coinID := action.CoinID
+		if coinID == "" {
+			coinID = "-"
+		}
+		if action.Action == mirrorActionAdd {
+			adds++
+		} else {
+			deletes++
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", action.Action, action.StoreID, coinID, action.Amount, strings.Join(action.URLs, ","))
+	}
+	_ = w.Flush()
+	_, _ = fmt.Fprintf(out, "\nPlan: %d to add, %d to delete\n", adds, deletes)
+}
+
+func init() {
+	mirrorsCmd.PersistentFlags().StringP("file", "f", "mirrors.yaml", "The desired state file")
+
+	cobra.CheckErr(viper.BindPFlag("mirrors-file", mirrorsCmd.PersistentFlags().Lookup("file")))
+
+	datalayerCmd.AddCommand(mirrorsCmd)
+}
@@ -0,0 +1,108 @@
+package datalayer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffMirrors(t *testing.T) {
+	current := []types.DatalayerMirror{
+		{CoinID: types.Bytes32{1}, Amount: 100, URLs: []string{"https://b.example.com", "https://a.example.com/"}, Ours: true},
+		{CoinID: types.Bytes32{2}, Amount: 100, URLs: []string{"https://old.example.com"}, Ours: true},
+		{CoinID: types.Bytes32{3}, Amount: 100, URLs: []string{"https://someone-else.example.com"}, Ours: false},
+	}
+	desired := []desiredMirror{
+		{URLs: []string{"https://A.example.com", "https://b.example.com"}},
+		{URLs: []string{"https://new.example.com"}, Amount: "0.000000001xck"},
+	}
+
+	actions, err := diffMirrors("abcd", desired, current)
+	assert.NoError(t, err)
+	assert.Equal(t, []mirrorAction{
+		{Action: mirrorActionAdd, StoreID: "abcd", URLs: []string{"https://new.example.com"}, Amount: 1000},
+		{Action: mirrorActionDelete, StoreID: "abcd", CoinID: types.Bytes32{2}.String(), URLs: []string{"https://old.example.com"}, Amount: 100},
+	}, actions)
+
+	_, err = diffMirrors("abcd", []desiredMirror{{}}, current)
+	assert.Error(t, err)
+}
+
+func TestLoadMirrorState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mirrors.yaml")
+	err := os.WriteFile(file, []byte(`stores:
+  all-owned:
+    - urls: ["https://dl.example.com"]
+      amount: 300
+`), 0644)
+	assert.NoError(t, err)
+
+	state, err := loadMirrorState(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []desiredMirror{{URLs: []string{"https://dl.example.com"}, Amount: "300"}}, state.Stores[allOwnedStores])
+}
+
+func TestMirrorTargets(t *testing.T) {
+	first := strings.Repeat("ab", 32)
+	second := strings.Repeat("cd", 32)
+	everywhere := []desiredMirror{{URLs: []string{"https://dl.example.com"}}}
+	override := []desiredMirror{{URLs: []string{"https://dl2.example.com"}, Amount: "300"}}
+
+	// The entry for the first store overrides all-owned even though it is written with 0x and in upper case
+	targets, err := mirrorTargets(&mirrorState{Stores: map[string][]desiredMirror{
+		allOwnedStores:                everywhere,
+		"0x" + strings.ToUpper(first): override,
+	}}, []string{first, "0x" + second})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]desiredMirror{first: override, second: everywhere}, targets)
+
+	_, err = mirrorTargets(&mirrorState{Stores: map[string][]desiredMirror{
+		first:        everywhere,
+		"0x" + first: override,
+	}}, nil)
+	assert.ErrorContains(t, err, "more than once")
+}
+
+func TestSubmittedMirrorActions(t *testing.T) {
+	add := mirrorAction{Action: mirrorActionAdd, StoreID: "abcd", URLs: []string{"https://dl.example.com"}, Amount: 100}
+	del := mirrorAction{Action: mirrorActionDelete, StoreID: "abcd", CoinID: types.Bytes32{2}.String()}
+	journal := []mirrorJournalEntry{
+		{Key: add.key(), Action: add},
+		{Key: add.key(), Action: add},
+		{Key: del.key(), Action: del},
+	}
+
+	// Both identical adds were submitted, but only one is on chain yet, and the delete is confirmed
+	submitted := submittedMirrorActions(journal, []mirrorAction{add})
+	assert.Equal(t, journal[:1], submitted)
+
+	// Once everything is confirmed the journal is empty, so a later plan adds the mirror again
+	assert.Empty(t, submittedMirrorActions(journal, nil))
+}
+
+func TestWriteMirrorJournal(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	add := mirrorAction{Action: mirrorActionAdd, StoreID: "abcd", URLs: []string{"https://dl.example.com"}, Amount: 100}
+	assert.NoError(t, appendMirrorJournal(file, add))
+	assert.NoError(t, appendMirrorJournal(file, add))
+
+	journal, err := loadMirrorJournal(file)
+	assert.NoError(t, err)
+	assert.Len(t, journal, 2)
+	assert.Equal(t, add, journal[1].Action)
+
+	assert.NoError(t, writeMirrorJournal(file, journal[:1]))
+	pruned, err := loadMirrorJournal(file)
+	assert.NoError(t, err)
+	assert.Equal(t, journal[:1], pruned)
+
+	assert.NoError(t, writeMirrorJournal(file, nil))
+	assert.NoFileExists(t, file)
+	journal, err = loadMirrorJournal(file)
+	assert.NoError(t, err)
+	assert.Empty(t, journal)
+}
@@ -0,0 +1,294 @@
+package datalayer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// mirrorJournalEntry is a single completed action in the progress journal
+type mirrorJournalEntry struct {
+	Key    string       `json:"key"`
+	Action mirrorAction `json:"action"`
+	Time   time.Time    `json:"time"`
+}
+
+// mirrorsApplyCmd adds and deletes mirrors to reach the desired mirror state
+var mirrorsApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Adds and deletes owned mirrors to match the desired state file",
+	Example: `chik-tools data mirrors apply -f mirrors.yaml -m 0.00001
+
+# Refuse to run if the fees for the whole plan would be more than 0.001 XCK
+chik-tools data mirrors apply -f mirrors.yaml -m auto --max-total-fee 0.001
+
+# Show what changes would be made without actually making them
+chik-tools data mirrors apply -f mirrors.yaml --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		state, err := loadMirrorState(viper.GetString("mirrors-file"))
+		if err != nil {
+			slogs.Logr.Fatal("error loading desired state file", "error", err)
+		}
+
+		journalFile := viper.GetString("mirrors-apply-journal")
+		journal, err := loadMirrorJournal(journalFile)
+		if err != nil {
+			slogs.Logr.Fatal("error reading progress journal", "file", journalFile, "error", err)
+		}
+
+		actions, err := planMirrors(client, state)
+		if err != nil {
+			slogs.Logr.Fatal("error planning mirror changes", "error", err)
+		}
+
+		// Entries for actions that are no longer in the plan have been confirmed on chain, so they are pruned
+		// Otherwise a completed add would suppress adding the same mirror again if it is deleted later
+		submitted := submittedMirrorActions(journal, actions)
+		if len(submitted) != len(journal) && !viper.GetBool("dry-run") {
+			slogs.Logr.Debug("Pruning confirmed actions from the progress journal", "file", journalFile, "pruned", len(journal)-len(submitted))
+			if err = writeMirrorJournal(journalFile, submitted); err != nil {
+				slogs.Logr.Warn("error pruning progress journal", "file", journalFile, "error", err)
+			}
+		}
+		if len(actions) == 0 {
+			slogs.Logr.Info("Mirrors already match the desired state")
+			return
+		}
+
+		// Each journal entry skips one matching action, so identical mirrors are each added once
+		done := map[string]int{}
+		for _, entry := range submitted {
+			done[entry.Key]++
+		}
+
+		// Adds go first, so the store doesn't lose mirrors while old ones are replaced
+		var pending []mirrorAction
+		var mirrorTotal uint64
+		for _, actionType := range []string{mirrorActionAdd, mirrorActionDelete} {
+			for _, action := range actions {
+				if action.Action != actionType {
+					continue
+				}
+				if done[action.key()] > 0 {
+					done[action.key()]--
+					slogs.Logr.Info("Skipping action already submitted in a previous run", "action", action.Action, "store", action.StoreID, "coin_id", action.CoinID)
+					continue
+				}
+				pending = append(pending, action)
+				if action.Action == mirrorActionAdd {
+					mirrorTotal += action.Amount
+				}
+			}
+		}
+		if len(pending) == 0 {
+			slogs.Logr.Info("All changes were submitted in a previous run and are waiting to be confirmed", "journal", journalFile)
+			return
+		}
+		printMirrorPlan(os.Stdout, pending)
+
+		feeMojos, err := resolveFee(client, viper.GetString("mirrors-apply-fee"), viper.GetUint64("mirrors-apply-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+		logFeeBudget(len(pending), feeMojos, mirrorTotal)
+
+		if maxFee := viper.GetString("mirrors-apply-max-total-fee"); maxFee != "" {
+			maxFeeMojos, err := utils.ParseAmount(maxFee, utils.UnitXCK)
+			if err != nil {
+				slogs.Logr.Fatal("invalid max total fee", "error", err)
+			}
+			totalFees := uint64(len(pending)) * feeMojos
+			if totalFees > maxFeeMojos {
+				slogs.Logr.Fatal("total fees for the plan are over the limit. No changes were made",
+					"total_fees_xck", utils.FormatXCK(totalFees),
+					"max_total_fee_xck", utils.FormatXCK(maxFeeMojos))
+			}
+		}
+
+		if viper.GetBool("dry-run") {
+			slogs.Logr.Info("DRY RUN: No changes were made to the mirrors")
+			return
+		}
+
+		if !utils.ConfirmAction(fmt.Sprintf("Apply %d mirror changes? (y/N)", len(pending)), viper.GetBool("mirrors-apply-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		for _, action := range pending {
+			err = applyMirrorAction(client, action, feeMojos)
+			if err != nil {
+				slogs.Logr.Fatal("error applying mirror change. Run the command again to resume",
+					"action", action.Action, "store", action.StoreID, "coin_id", action.CoinID, "error", err)
+			}
+			err = appendMirrorJournal(journalFile, action)
+			if err != nil {
+				slogs.Logr.Fatal("error writing progress journal", "file", journalFile, "error", err)
+			}
+		}
+
+		slogs.Logr.Info("Submitted all mirror changes", "count", len(pending), "journal", journalFile)
+	},
+}
+
+// applyMirrorAction sends the transaction for a single add or delete once the wallet can pay for it
+func applyMirrorAction(client *rpc.Client, action mirrorAction, feeMojos uint64) error {
+	if action.Action == mirrorActionAdd {
+		waitForAvailableBalance(client, action.Amount+feeMojos)
+		slogs.Logr.Info("adding mirror", "store", action.StoreID, "urls", action.URLs, "amount", action.Amount)
+		resp, _, err := client.DataLayerService.AddMirror(&rpc.DatalayerAddMirrorOptions{
+			ID:     action.StoreID,
+			URLs:   action.URLs,
+			Amount: action.Amount,
+			Fee:    feeMojos,
+		})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("unknown error when adding mirror")
+		}
+		return nil
+	}
+
+	waitForAvailableBalance(client, feeMojos)
+	slogs.Logr.Info("deleting mirror", "store", action.StoreID, "coin_id", action.CoinID, "urls", action.URLs)
+	resp, _, err := client.DataLayerService.DeleteMirror(&rpc.DatalayerDeleteMirrorOptions{
+		CoinID: action.CoinID,
+		Fee:    feeMojos,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("unknown error when deleting mirror")
+	}
+	return nil
+}
+
+// loadMirrorJournal returns every action recorded in the journal
+// A missing journal is not an error
+func loadMirrorJournal(file string) ([]mirrorJournalEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []mirrorJournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := mirrorJournalEntry{}
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// submittedMirrorActions returns the journal entries that are still waiting to be confirmed
+// An action is confirmed once the plan no longer needs it, so each key keeps at most as many entries as the plan has actions for it
+func submittedMirrorActions(journal []mirrorJournalEntry, actions []mirrorAction) []mirrorJournalEntry {
+	planned := map[string]int{}
+	for _, action := range actions {
+		planned[action.key()]++
+	}
+
+	var submitted []mirrorJournalEntry
+	for _, entry := range journal {
+		if planned[entry.Key] == 0 {
+			continue
+		}
+		planned[entry.Key]--
+		submitted = append(submitted, entry)
+	}
+	return submitted
+}
+
+// writeMirrorJournal replaces the journal with the given entries, removing it when there are none
+func writeMirrorJournal(file string, entries []mirrorJournalEntry) error {
+	if len(entries) == 0 {
+		err := os.Remove(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var content []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		content = append(append(content, line...), '\n')
+	}
+	tmpFile := file + ".tmp"
+	err := os.WriteFile(tmpFile, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
+
+// appendMirrorJournal records a completed action, so it won't be sent again if the run is resumed
+func appendMirrorJournal(file string, action mirrorAction) error {
+	line, err := json.Marshal(mirrorJournalEntry{Key: action.key(), Action: action, Time: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	addFeeFlags(mirrorsApplyCmd, "Fee to use for each mirror transaction")
+	mirrorsApplyCmd.PersistentFlags().String("max-total-fee", "", "Refuse to make any changes if the total fees for the plan are over this amount. Amounts are XCK unless they end in mojos")
+	mirrorsApplyCmd.PersistentFlags().String("journal", "mirrors-apply-journal.jsonl", "Progress journal used to resume an interrupted run without repeating transactions")
+	mirrorsApplyCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+
+	cobra.CheckErr(viper.BindPFlag("mirrors-apply-fee", mirrorsApplyCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("mirrors-apply-fee-target-time", mirrorsApplyCmd.PersistentFlags().Lookup("fee-target-time")))
+	cobra.CheckErr(viper.BindPFlag("mirrors-apply-max-total-fee", mirrorsApplyCmd.PersistentFlags().Lookup("max-total-fee")))
+	cobra.CheckErr(viper.BindPFlag("mirrors-apply-journal", mirrorsApplyCmd.PersistentFlags().Lookup("journal")))
+	cobra.CheckErr(viper.BindPFlag("mirrors-apply-yes", mirrorsApplyCmd.PersistentFlags().Lookup("yes")))
+
+	mirrorsCmd.AddCommand(mirrorsApplyCmd)
+}
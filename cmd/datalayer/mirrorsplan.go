@@ -0,0 +1,39 @@
+package datalayer
+
+import (
+	"os"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// mirrorsPlanCmd shows the changes needed to reach the desired mirror state
+var mirrorsPlanCmd = &cobra.Command{
+	Use:     "plan",
+	Short:   "Shows the mirrors that would be added and deleted to match the desired state file",
+	Example: `chik-tools data mirrors plan -f mirrors.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		state, err := loadMirrorState(viper.GetString("mirrors-file"))
+		if err != nil {
+			slogs.Logr.Fatal("error loading desired state file", "error", err)
+		}
+
+		actions, err := planMirrors(client, state)
+		if err != nil {
+			slogs.Logr.Fatal("error planning mirror changes", "error", err)
+		}
+
+		printMirrorPlan(os.Stdout, actions)
+	},
+}
+
+func init() {
+	mirrorsCmd.AddCommand(mirrorsPlanCmd)
+}
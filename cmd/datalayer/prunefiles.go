@@ -0,0 +1,202 @@
+package datalayer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// Reasons a server file can be pruned
+const (
+	pruneUnsubscribed  = "unsubscribed"
+	pruneOldGeneration = "old generation"
+)
+
+// prunableFile is a server file that can be deleted, and why
+type prunableFile struct {
+	serverFile
+	Reason string
+}
+
+// pruneFilesCmd deletes server files that are no longer needed
+var pruneFilesCmd = &cobra.Command{
+	Use:   "prune-files",
+	Short: "Deletes server files for stores that are no longer subscribed, and optionally old generations",
+	Example: `# Show the files for unsubscribed stores and how much space they use
+chik-tools data prune-files --dry-run
+
+# Also delete files outside the newest 10 generations of each store
+chik-tools data prune-files --keep-generations 10`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		location := viper.GetString("prune-files-location")
+		if location == "" {
+			location, err = serverFilesLocation()
+			if err != nil {
+				slogs.Logr.Fatal("error finding server_files_location", "error", err)
+			}
+		}
+		slogs.Logr.Info("Checking server files", "location", location)
+
+		files, err := listServerFiles(location)
+		if err != nil {
+			slogs.Logr.Fatal("error listing server files", "location", location, "error", err)
+		}
+
+		owned, _, err := client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
+		if err != nil {
+			slogs.Logr.Fatal("error getting list of owned data stores", "error", err)
+		}
+		subscriptions, _, err := client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+		if err != nil {
+			slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
+		}
+		active := append(slices.Clone(owned.StoreIDs), subscriptions.StoreIDs...)
+
+		prunable := findPrunableFiles(files, active, viper.GetUint64("prune-files-keep-generations"))
+		var reclaimable int64
+		for _, file := range prunable {
+			reclaimable += file.Size
+		}
+		printPrunableFiles(os.Stdout, prunable)
+		fmt.Printf("\n%d files, %s reclaimable\n", len(prunable), formatBytes(reclaimable))
+
+		if len(prunable) == 0 {
+			return
+		}
+		if viper.GetBool("dry-run") {
+			slogs.Logr.Info("DRY RUN: No files were deleted")
+			return
+		}
+		if !utils.ConfirmAction(fmt.Sprintf("Delete %d files (%s)? (y/N)", len(prunable), formatBytes(reclaimable)), viper.GetBool("prune-files-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		var deleted int64
+		for _, file := range prunable {
+			slogs.Logr.Debug("Deleting file", "path", file.Path, "reason", file.Reason)
+			if err := os.Remove(file.Path); err != nil {
+				slogs.Logr.Error("error deleting file", "path", file.Path, "error", err)
+				continue
+			}
+			deleted += file.Size
+		}
+		slogs.Logr.Info("Deleted server files", "reclaimed", formatBytes(deleted))
+	},
+}
+
+// findPrunableFiles returns files for stores that aren't active, and files outside the newest keepGenerations
+// generations on disk for each store. A keepGenerations of 0 keeps every generation of active stores
+func findPrunableFiles(files []serverFile, activeStores []string, keepGenerations uint64) []prunableFile {
+	latest := map[string]uint64{}
+	for _, file := range files {
+		if file.Generation > latest[file.StoreID] {
+			latest[file.StoreID] = file.Generation
+		}
+	}
+
+	var prunable []prunableFile
+	for _, file := range files {
+		switch {
+		case !containsStoreID(activeStores, file.StoreID):
+			prunable = append(prunable, prunableFile{serverFile: file, Reason: pruneUnsubscribed})
+		case keepGenerations > 0 && file.Generation+keepGenerations <= latest[file.StoreID]:
+			prunable = append(prunable, prunableFile{serverFile: file, Reason: pruneOldGeneration})
+		}
+	}
+
+	sort.SliceStable(prunable, func(i, j int) bool {
+		if prunable[i].StoreID != prunable[j].StoreID {
+			return prunable[i].StoreID < prunable[j].StoreID
+		}
+		return prunable[i].Generation < prunable[j].Generation
+	})
+	return prunable
+}
+
+// containsStoreID checks for the store ID, ignoring any 0x prefix and case
+func containsStoreID(storeIDs []string, storeID string) bool {
+	for _, id := range storeIDs {
+		if normalizeStoreID(id) == normalizeStoreID(storeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// printPrunableFiles writes a summary of the prunable files for each store as a table
+func printPrunableFiles(out io.Writer, prunable []prunableFile) {
+	type summaryKey struct {
+		storeID string
+		reason  string
+	}
+	type summary struct {
+		files int
+		bytes int64
+		first uint64
+		last  uint64
+	}
+	var order []summaryKey
+	summaries := map[summaryKey]*summary{}
+	for _, file := range prunable {
+		key := summaryKey{storeID: file.StoreID, reason: file.Reason}
+		s, ok := summaries[key]
+		if !ok {
+			s = &summary{first: file.Generation}
+			summaries[key] = s
+			order = append(order, key)
+		}
+		s.files++
+		s.bytes += file.Size
+		s.last = file.Generation
+	}
+
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STORE\tREASON\tGENERATIONS\tFILES\tSIZE")
+	for _, key := range order {
+		s := summaries[key]
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d-%d\t%d\t%s\n", key.storeID, key.reason, s.first, s.last, s.files, formatBytes(s.bytes))
+	}
+	_ = w.Flush()
+}
+
+// formatBytes formats a size in bytes using binary units
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	pruneFilesCmd.PersistentFlags().Uint64("keep-generations", 0, "Also delete files outside the newest N generations of each store. 0 keeps all generations")
+	pruneFilesCmd.PersistentFlags().String("location", "", "Override the server_files_location directory from the chik config")
+	pruneFilesCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+
+	cobra.CheckErr(viper.BindPFlag("prune-files-keep-generations", pruneFilesCmd.PersistentFlags().Lookup("keep-generations")))
+	cobra.CheckErr(viper.BindPFlag("prune-files-location", pruneFilesCmd.PersistentFlags().Lookup("location")))
+	cobra.CheckErr(viper.BindPFlag("prune-files-yes", pruneFilesCmd.PersistentFlags().Lookup("yes")))
+
+	datalayerCmd.AddCommand(pruneFilesCmd)
+}
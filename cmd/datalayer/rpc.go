@@ -0,0 +1,312 @@
+package datalayer
+
+import (
+	"fmt"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/rpcinterface"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+)
+
+// dataLayerResponse holds the fields common to every data layer RPC response
+type dataLayerResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (r *dataLayerResponse) err() error {
+	if r.Success {
+		return nil
+	}
+	if r.Error != "" {
+		return fmt.Errorf("%s", r.Error)
+	}
+	return fmt.Errorf("unknown error")
+}
+
+// responseWithError is implemented by every response type in this file
+type responseWithError interface {
+	err() error
+}
+
+// permanentError is a failure that retrying won't fix, such as a request the data layer or a webhook rejected
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// storeIDOptions is the request for RPCs that only take a store ID
+type storeIDOptions struct {
+	ID string `json:"id"`
+}
+
+// getRootResponse is the response from get_root
+type getRootResponse struct {
+	dataLayerResponse
+	Confirmed bool   `json:"confirmed"`
+	Hash      string `json:"hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// dataLayerRequest calls an arbitrary data layer RPC endpoint that isn't wrapped by the RPC client
+// An error is returned if the request fails, or the response indicates it was not successful
+func dataLayerRequest(client *rpc.Client, endpoint rpcinterface.Endpoint, opts any, v responseWithError) error {
+	req, err := client.DataLayerService.NewRequest(endpoint, opts)
+	if err != nil {
+		return fmt.Errorf("error creating %s request: %w", endpoint, err)
+	}
+	_, err = client.DataLayerService.Do(req, v)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", endpoint, err)
+	}
+	if err = v.err(); err != nil {
+		return fmt.Errorf("%s was not successful: %w", endpoint, err)
+	}
+	return nil
+}
+
+// storeStateSource reads the sync status and latest root of a store
+type storeStateSource interface {
+	syncStatus(storeID string) (*syncStatus, error)
+	root(storeID string) (*getRootResponse, error)
+}
+
+// rpcStoreState reads the state of a store from the data layer RPC
+type rpcStoreState struct {
+	client *rpc.Client
+}
+
+func (s rpcStoreState) syncStatus(storeID string) (*syncStatus, error) {
+	return getSyncStatus(s.client, storeID)
+}
+
+func (s rpcStoreState) root(storeID string) (*getRootResponse, error) {
+	return getRoot(s.client, storeID)
+}
+
+// getRoot returns the current root of the store
+func getRoot(client *rpc.Client, storeID string) (*getRootResponse, error) {
+	resp := &getRootResponse{}
+	err := dataLayerRequest(client, "get_root", &storeIDOptions{ID: storeID}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// syncStatus is the sync status of a single store
+type syncStatus struct {
+	Generation       uint64 `json:"generation"`
+	RootHash         string `json:"root_hash"`
+	TargetGeneration uint64 `json:"target_generation"`
+	TargetRootHash   string `json:"target_root_hash"`
+}
+
+// getSyncStatusResponse is the response from get_sync_status
+type getSyncStatusResponse struct {
+	dataLayerResponse
+	SyncStatus syncStatus `json:"sync_status"`
+}
+
+// getSyncStatus returns the local and target generation of the store
+func getSyncStatus(client *rpc.Client, storeID string) (*syncStatus, error) {
+	resp := &getSyncStatusResponse{}
+	err := dataLayerRequest(client, "get_sync_status", &storeIDOptions{ID: storeID}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.SyncStatus, nil
+}
+
+// rootHistoryEntry is a single generation of a store
+type rootHistoryEntry struct {
+	RootHash  string `json:"root_hash"`
+	Confirmed bool   `json:"confirmed"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// getRootHistoryResponse is the response from get_root_history
+type getRootHistoryResponse struct {
+	dataLayerResponse
+	RootHistory []rootHistoryEntry `json:"root_history"`
+}
+
+// latestConfirmedGeneration returns the generation of the last confirmed root in the history, or -1 if there isn't one
+func latestConfirmedGeneration(history []rootHistoryEntry) int {
+	latest := -1
+	for idx, entry := range history {
+		if !entry.Confirmed {
+			break
+		}
+		latest = idx
+	}
+	return latest
+}
+
+// getRootHistory returns every root of the store, oldest first, so the index of each entry is its generation
+func getRootHistory(client *rpc.Client, storeID string) ([]rootHistoryEntry, error) {
+	resp := &getRootHistoryResponse{}
+	err := dataLayerRequest(client, "get_root_history", &storeIDOptions{ID: storeID}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.RootHistory, nil
+}
+
+// getKeysValuesPageOptions is the request for a single page of get_keys_values
+type getKeysValuesPageOptions struct {
+	ID          string `json:"id"`
+	RootHash    string `json:"root_hash,omitempty"`
+	Page        int    `json:"page"`
+	MaxPageSize int    `json:"max_page_size"`
+}
+
+// getKeysValuesPageResponse is a single page of get_keys_values
+type getKeysValuesPageResponse struct {
+	dataLayerResponse
+	KeysValues []types.DatalayerKeyValue `json:"keys_values"`
+	TotalPages int                       `json:"total_pages"`
+	TotalBytes int                       `json:"total_bytes"`
+}
+
+// forEachKeyValue calls fn for every key and value in the store, one page at a time, so large stores don't have to be
+// held in memory. When rootHash is empty, the current root is used
+func forEachKeyValue(client *rpc.Client, storeID, rootHash string, pageSize int, fn func(types.DatalayerKeyValue) error) error {
+	for page := 0; ; page++ {
+		resp := &getKeysValuesPageResponse{}
+		err := dataLayerRequest(client, "get_keys_values", &getKeysValuesPageOptions{
+			ID:          storeID,
+			RootHash:    rootHash,
+			Page:        page,
+			MaxPageSize: pageSize,
+		}, resp)
+		if err != nil {
+			return err
+		}
+		for _, kv := range resp.KeysValues {
+			if err = fn(kv); err != nil {
+				return err
+			}
+		}
+		if page+1 >= resp.TotalPages {
+			return nil
+		}
+	}
+}
+
+// Actions in a batch_update changelist
+const (
+	changeInsert = "insert"
+	changeUpsert = "upsert"
+	changeDelete = "delete"
+)
+
+// changelistEntry is a single change in a batch_update. Keys and values are hex
+type changelistEntry struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+}
+
+// batchUpdateOptions is the request for batch_update
+type batchUpdateOptions struct {
+	ID         string            `json:"id"`
+	Changelist []changelistEntry `json:"changelist"`
+	Fee        uint64            `json:"fee"`
+}
+
+// batchUpdateResponse is the response from batch_update
+type batchUpdateResponse struct {
+	dataLayerResponse
+	TxID string `json:"tx_id"`
+}
+
+// batchUpdate submits the changelist to the store in a single transaction
+func batchUpdate(client *rpc.Client, storeID string, changelist []changelistEntry, fee uint64) (*batchUpdateResponse, error) {
+	resp := &batchUpdateResponse{}
+	err := dataLayerRequest(client, "batch_update", &batchUpdateOptions{ID: storeID, Changelist: changelist, Fee: fee}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// createDataStoreOptions is the request for create_data_store
+type createDataStoreOptions struct {
+	Fee uint64 `json:"fee"`
+}
+
+// createDataStoreResponse is the response from create_data_store
+type createDataStoreResponse struct {
+	dataLayerResponse
+	ID string `json:"id"`
+}
+
+// createDataStore creates a new empty store owned by this wallet and returns its ID
+func createDataStore(client *rpc.Client, fee uint64) (string, error) {
+	resp := &createDataStoreResponse{}
+	err := dataLayerRequest(client, "create_data_store", &createDataStoreOptions{Fee: fee}, resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// Types of entries in a kv diff
+const (
+	diffInsert = "INSERT"
+	diffDelete = "DELETE"
+)
+
+// kvDiffEntry is a single inserted or deleted key in a kv diff
+type kvDiffEntry struct {
+	Type  string      `json:"type"`
+	Key   types.Bytes `json:"key"`
+	Value types.Bytes `json:"value"`
+}
+
+// getKVDiffOptions is the request for a single page of get_kv_diff
+type getKVDiffOptions struct {
+	ID          string `json:"id"`
+	Hash1       string `json:"hash_1"`
+	Hash2       string `json:"hash_2"`
+	Page        int    `json:"page"`
+	MaxPageSize int    `json:"max_page_size"`
+}
+
+// getKVDiffResponse is a single page of get_kv_diff
+type getKVDiffResponse struct {
+	dataLayerResponse
+	Diff       []kvDiffEntry `json:"diff"`
+	TotalPages int           `json:"total_pages"`
+}
+
+// getKVDiff returns every key inserted and deleted between two root hashes of the store
+// Changed values show up as a delete of the old value and an insert of the new value
+func getKVDiff(client *rpc.Client, storeID, fromHash, toHash string, pageSize int) ([]kvDiffEntry, error) {
+	var diff []kvDiffEntry
+	for page := 0; ; page++ {
+		resp := &getKVDiffResponse{}
+		err := dataLayerRequest(client, "get_kv_diff", &getKVDiffOptions{
+			ID:          storeID,
+			Hash1:       fromHash,
+			Hash2:       toHash,
+			Page:        page,
+			MaxPageSize: pageSize,
+		}, resp)
+		if err != nil {
+			return nil, err
+		}
+		diff = append(diff, resp.Diff...)
+		if page+1 >= resp.TotalPages {
+			return diff, nil
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package datalayer
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd serves server_files_location over HTTP so it can be used as a mirror
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves the data layer server files over HTTP so they can be used as a mirror",
+	Long: `Serves the data layer server files over HTTP so they can be used as a mirror.
+
+Only full and delta files are served, in the flat or grouped by store layout. Range requests
+are supported, and every request is logged. Use --allow-store to only serve some stores.
+The default port is 8576, since 8575 is the data layer's own file server port (data_layer.host_port).
+
+Point an owned mirror at this server by adding its public URL to the mirrors state file and
+running data mirrors apply, for example:
+
+  stores:
+    all-owned:
+      - urls: ["http://203.0.113.10:8576"]`,
+	Example: `chik-tools data serve --listen :8576
+
+# Only serve two stores, one listed directly and others from a file of store IDs
+chik-tools data serve --listen :8576 --allow-store abcd1234 --allow-store stores.txt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		location := viper.GetString("serve-location")
+		if location == "" {
+			location, err = serverFilesLocation()
+			if err != nil {
+				slogs.Logr.Fatal("error finding server_files_location", "error", err)
+			}
+		}
+
+		allowed, err := readIDList(viper.GetStringSlice("serve-allow-store"))
+		if err != nil {
+			slogs.Logr.Fatal("error reading allowed store IDs", "error", err)
+		}
+
+		server := &http.Server{
+			Addr:              viper.GetString("serve-listen"),
+			Handler:           logServerFileRequests(newServerFileHandler(location, allowed)),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		slogs.Logr.Info("Serving server files", "location", location, "listen", server.Addr, "allowed_stores", len(allowed))
+		err = server.ListenAndServe()
+		if err != nil {
+			slogs.Logr.Fatal("error serving server files", "error", err)
+		}
+	},
+}
+
+// newServerFileHandler returns a handler that serves data layer server files from location
+// When allowedStores is not empty, files for any other store are not found
+func newServerFileHandler(location string, allowedStores []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Only names that parse as server files are served, which also rules out directory listings and ..
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		file, ok := parseServerFileName(name)
+		if !ok || (len(allowedStores) > 0 && !containsStoreID(allowedStores, file.StoreID)) {
+			http.NotFound(w, r)
+			return
+		}
+
+		f, err := os.Open(filepath.Join(location, filepath.FromSlash(name)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer func() { _ = f.Close() }()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Server files never change once written, so clients and proxies can cache them
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		http.ServeContent(w, r, name, info.ModTime(), f)
+	})
+}
+
+// accessLogWriter records the status and size of a response for the access log
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// logServerFileRequests wraps the handler with an access log
+func logServerFileRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(lw, r)
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+		slogs.Logr.Info("request",
+			"remote", r.RemoteAddr,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"range", r.Header.Get("Range"),
+			"status", lw.status,
+			"bytes", lw.bytes,
+			"duration", time.Since(start),
+			"user_agent", r.UserAgent(),
+		)
+	})
+}
+
+func init() {
+	serveCmd.PersistentFlags().String("listen", ":8576", "Address to listen on")
+	serveCmd.PersistentFlags().String("location", "", "Override the server_files_location directory from the chik config")
+	serveCmd.PersistentFlags().StringSlice("allow-store", nil, "Only serve files for this store ID, or the store IDs in this file. Can be used more than once")
+
+	cobra.CheckErr(viper.BindPFlag("serve-listen", serveCmd.PersistentFlags().Lookup("listen")))
+	cobra.CheckErr(viper.BindPFlag("serve-location", serveCmd.PersistentFlags().Lookup("location")))
+	cobra.CheckErr(viper.BindPFlag("serve-allow-store", serveCmd.PersistentFlags().Lookup("allow-store")))
+
+	datalayerCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,100 @@
+package datalayer
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerFileHandler(t *testing.T) {
+	storeA := strings.Repeat("a", 64)
+	storeB := strings.Repeat("b", 64)
+	root := strings.Repeat("c", 64)
+	dir := t.TempDir()
+	fileA := serverFileName(storeA, root, serverFileFull, 1, false)
+	fileB := serverFileName(storeB, root, serverFileFull, 1, true)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, fileA), []byte("0123456789"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, storeB), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(fileB)), []byte("grouped"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("secret"), 0644))
+
+	handler := newServerFileHandler(dir, nil)
+	get := func(h http.Handler, target, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(handler, "/"+fileA, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "0123456789", rec.Body.String())
+
+	rec = get(handler, "/"+fileA, "bytes=2-4")
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "234", rec.Body.String())
+
+	rec = get(handler, "/"+fileB, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "grouped", rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get(handler, "/config.yaml", "").Code)
+	assert.Equal(t, http.StatusNotFound, get(handler, "/", "").Code)
+
+	allowed := newServerFileHandler(dir, []string{"0x" + storeA})
+	assert.Equal(t, http.StatusOK, get(allowed, "/"+fileA, "").Code)
+	assert.Equal(t, http.StatusNotFound, get(allowed, "/"+fileB, "").Code)
+}
+
+func TestLogServerFileRequests(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slogs.Logr
+	slogs.Logr = slogs.Logger{Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	defer func() {
+		slogs.Logr = previous
+	}()
+
+	handler := logServerFileRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/partial":
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("234"))
+		case "/empty":
+		default:
+			_, _ = w.Write([]byte("0123456789"))
+		}
+	}))
+
+	for _, tt := range []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/full", http.StatusOK, "status=200 bytes=10"},
+		{"/partial", http.StatusPartialContent, "status=206 bytes=3"},
+		{"/empty", http.StatusOK, "status=200 bytes=0"},
+	} {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Range", "bytes=2-4")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, tt.status, rec.Code, tt.path)
+		assert.Contains(t, logs.String(), "path="+tt.path, tt.path)
+		assert.Contains(t, logs.String(), `range="bytes=2-4"`, tt.path)
+		assert.Contains(t, logs.String(), tt.expected, tt.path)
+	}
+}
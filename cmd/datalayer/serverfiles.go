@@ -0,0 +1,177 @@
+package datalayer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of files data layer writes to server_files_location
+const (
+	serverFileFull  = "full"
+	serverFileDelta = "delta"
+)
+
+// serverFileRegex matches the name of a full or delta file in either the flat or grouped by store layout
+var serverFileRegex = regexp.MustCompile(`^([0-9a-f]{64})[-/]([0-9a-f]{64})-(full|delta)-(\d+)-v1\.0\.dat$`)
+
+// serverFile is a full or delta file in server_files_location
+type serverFile struct {
+	Path       string
+	StoreID    string
+	RootHash   string
+	Kind       string
+	Generation uint64
+	Size       int64
+}
+
+// dataLayerFileConfig is the part of the data_layer config needed to find server files and the database
+type dataLayerFileConfig struct {
+	SelectedNetwork string `yaml:"selected_network"`
+	DataLayer       struct {
+		SelectedNetwork     string `yaml:"selected_network"`
+		DatabasePath        string `yaml:"database_path"`
+		ServerFilesLocation string `yaml:"server_files_location"`
+		GroupFilesByStore   bool   `yaml:"group_files_by_store"`
+	} `yaml:"data_layer"`
+}
+
+// serverFileName returns the name of a full or delta file in the same format data layer uses when writing and
+// downloading them. When groupByStore is set, files live in a directory named after the store
+func serverFileName(storeID, rootHash, kind string, generation uint64, groupByStore bool) string {
+	storeID = strings.TrimPrefix(storeID, "0x")
+	rootHash = strings.TrimPrefix(rootHash, "0x")
+	if groupByStore {
+		return fmt.Sprintf("%s/%s-%s-%d-v1.0.dat", storeID, rootHash, kind, generation)
+	}
+	return fmt.Sprintf("%s-%s-%s-%d-v1.0.dat", storeID, rootHash, kind, generation)
+}
+
+// normalizeStoreID returns the store ID in lowercase without a 0x prefix, as used in server file names
+func normalizeStoreID(storeID string) string {
+	return strings.ToLower(strings.TrimPrefix(storeID, "0x"))
+}
+
+// parseServerFileName parses a path relative to server_files_location
+// The second return value is false when the path isn't a data layer server file
+func parseServerFileName(name string) (serverFile, bool) {
+	match := serverFileRegex.FindStringSubmatch(filepath.ToSlash(name))
+	if match == nil {
+		return serverFile{}, false
+	}
+	generation, err := strconv.ParseUint(match[4], 10, 64)
+	if err != nil {
+		return serverFile{}, false
+	}
+	return serverFile{
+		Path:       name,
+		StoreID:    match[1],
+		RootHash:   match[2],
+		Kind:       match[3],
+		Generation: generation,
+	}, true
+}
+
+// serverFilesLocation returns the absolute path of server_files_location from the chik config
+func serverFilesLocation() (string, error) {
+	chikRoot, cfg, err := loadDataLayerFileConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.resolvePath(chikRoot, cfg.DataLayer.ServerFilesLocation, "data_layer/db/server_files_location_CHALLENGE"), nil
+}
+
+// dataLayerDatabasePath returns the absolute path of the data layer database from the chik config
+func dataLayerDatabasePath() (string, error) {
+	chikRoot, cfg, err := loadDataLayerFileConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.resolvePath(chikRoot, cfg.DataLayer.DatabasePath, "data_layer/db/data_layer_CHALLENGE.sqlite"), nil
+}
+
+// loadDataLayerFileConfig reads the config.yaml in CHIK_ROOT
+func loadDataLayerFileConfig() (string, *dataLayerFileConfig, error) {
+	chikRoot, err := config.GetChikRootPath()
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to determine CHIK_ROOT: %w", err)
+	}
+	cfg, err := readDataLayerFileConfig(filepath.Join(chikRoot, "config", "config.yaml"))
+	if err != nil {
+		return "", nil, err
+	}
+	return chikRoot, cfg, nil
+}
+
+// readDataLayerFileConfig parses the data layer settings from a config.yaml
+func readDataLayerFileConfig(cfgPath string) (*dataLayerFileConfig, error) {
+	cfgBytes, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	cfg := &dataLayerFileConfig{}
+	err = yaml.Unmarshal(cfgBytes, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+	return cfg, nil
+}
+
+// resolvePath expands a data layer path setting the same way data layer does. CHALLENGE is replaced with the
+// selected_network of the data_layer section, a leading ~ is the home directory, and relative paths are in CHIK_ROOT
+func (cfg *dataLayerFileConfig) resolvePath(chikRoot, configured, defaultPath string) string {
+	location := configured
+	if location == "" {
+		location = defaultPath
+	}
+	network := cfg.DataLayer.SelectedNetwork
+	if network == "" {
+		network = cfg.SelectedNetwork
+	}
+	location = strings.ReplaceAll(location, "CHALLENGE", network)
+	if location == "~" || strings.HasPrefix(location, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			location = filepath.Join(home, strings.TrimPrefix(location, "~"))
+		}
+	}
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(chikRoot, location)
+	}
+	return location
+}
+
+// listServerFiles returns every full and delta file in the directory
+func listServerFiles(location string) ([]serverFile, error) {
+	var files []serverFile
+	err := filepath.WalkDir(location, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(location, path)
+		if err != nil {
+			return err
+		}
+		file, ok := parseServerFileName(rel)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file.Path = path
+		file.Size = info.Size()
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,72 @@
+package datalayer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServerFileName(t *testing.T) {
+	storeID := strings.Repeat("ab", 32)
+	rootHash := strings.Repeat("cd", 32)
+
+	for _, groupByStore := range []bool{false, true} {
+		name := serverFileName("0x"+storeID, "0x"+rootHash, serverFileDelta, 12, groupByStore)
+		file, ok := parseServerFileName(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, serverFile{Path: name, StoreID: storeID, RootHash: rootHash, Kind: serverFileDelta, Generation: 12}, file)
+	}
+
+	_, ok := parseServerFileName("notes.txt")
+	assert.False(t, ok)
+}
+
+func TestFindPrunableFiles(t *testing.T) {
+	active := strings.Repeat("aa", 32)
+	removed := strings.Repeat("bb", 32)
+	files := []serverFile{
+		{StoreID: active, Generation: 1},
+		{StoreID: active, Generation: 2},
+		{StoreID: active, Generation: 3},
+		{StoreID: removed, Generation: 1},
+	}
+
+	prunable := findPrunableFiles(files, []string{"0x" + active}, 0)
+	assert.Equal(t, []prunableFile{{serverFile: files[3], Reason: pruneUnsubscribed}}, prunable)
+
+	prunable = findPrunableFiles(files, []string{"0x" + active}, 2)
+	assert.Equal(t, []prunableFile{
+		{serverFile: files[0], Reason: pruneOldGeneration},
+		{serverFile: files[3], Reason: pruneUnsubscribed},
+	}, prunable)
+}
+
+func TestResolveDataLayerPath(t *testing.T) {
+	chikRoot := t.TempDir()
+	cfgPath := filepath.Join(chikRoot, "config.yaml")
+
+	// Data layer expands CHALLENGE with the selected_network of its own section, which chik sets when switching networks
+	err := os.WriteFile(cfgPath, []byte(`selected_network: mainnet
+data_layer:
+  selected_network: testnet11
+  database_path: data_layer/db/data_layer_CHALLENGE.sqlite
+  server_files_location: /srv/dl/CHALLENGE
+`), 0644)
+	assert.NoError(t, err)
+	cfg, err := readDataLayerFileConfig(cfgPath)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(chikRoot, "data_layer/db/data_layer_testnet11.sqlite"), cfg.resolvePath(chikRoot, cfg.DataLayer.DatabasePath, ""))
+	assert.Equal(t, "/srv/dl/testnet11", cfg.resolvePath(chikRoot, cfg.DataLayer.ServerFilesLocation, ""))
+
+	err = os.WriteFile(cfgPath, []byte(`selected_network: testnet11
+data_layer: {}
+`), 0644)
+	assert.NoError(t, err)
+	cfg, err = readDataLayerFileConfig(cfgPath)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(chikRoot, "data_layer/db/server_files_location_testnet11"),
+		cfg.resolvePath(chikRoot, cfg.DataLayer.ServerFilesLocation, "data_layer/db/server_files_location_CHALLENGE"))
+}
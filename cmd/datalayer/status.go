@@ -0,0 +1,286 @@
+package datalayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Store states shown by the status command
+const (
+	stateSynced  = "synced"
+	stateStalled = "stalled"
+	statePending = "pending"
+	stateError   = "error"
+)
+
+// storeStatus is the sync state of a single store
+// Stalled is only set when the store is known to be behind its target generation. Stores that couldn't be read have
+// the error state instead, and newly subscribed stores that haven't received their first root are pending
+type storeStatus struct {
+	StoreID          string `json:"store_id"`
+	Owned            bool   `json:"owned"`
+	Generation       uint64 `json:"generation"`
+	TargetGeneration uint64 `json:"target_generation"`
+	RootHash         string `json:"root_hash"`
+	Timestamp        int64  `json:"timestamp"`
+	Mirrors          int    `json:"mirrors"`
+	State            string `json:"state"`
+	Stalled          bool   `json:"stalled"`
+	Error            string `json:"error,omitempty"`
+}
+
+// statusCmd shows the sync status of every store
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the sync status, latest root and mirror count for every subscribed and owned store",
+	Example: `chik-tools data status
+chik-tools data status --as-json
+
+# Refresh every 30 seconds
+chik-tools data status --watch 30s
+
+# Only show stores that are behind their target generation, exiting 1 if there are any
+chik-tools data status --stalled`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		watch := viper.GetDuration("status-watch")
+		for {
+			statuses, err := collectStoreStatuses(rpcStatusSource{rpcStoreState{client: client}}, viper.GetInt("status-concurrency"))
+			if err != nil {
+				if watch <= 0 {
+					slogs.Logr.Fatal("error getting store status", "error", err)
+				}
+				// The data layer may be restarting, so keep watching and try again on the next refresh
+				slogs.Logr.Error("error getting store status, retrying", "error", err, "retry_in", watch)
+				time.Sleep(watch)
+				continue
+			}
+
+			shown, stalled, errored := filterStoreStatuses(statuses, viper.GetBool("status-stalled"))
+
+			if watch > 0 && !viper.GetBool("status-as-json") {
+				// Clear the screen before redrawing
+				fmt.Print("\033[H\033[2J")
+				fmt.Printf("Updated %s, refreshing every %s\n\n", time.Now().Format(time.RFC3339), watch)
+			}
+			if viper.GetBool("status-as-json") {
+				output, err := json.MarshalIndent(shown, "", "  ")
+				if err != nil {
+					slogs.Logr.Fatal("error marshaling output to JSON", "error", err)
+				}
+				fmt.Println(string(output))
+			} else {
+				printStoreStatuses(os.Stdout, shown)
+				fmt.Printf("\n%d stores, %d stalled, %d errors\n", len(statuses), stalled, errored)
+			}
+
+			if watch <= 0 {
+				if viper.GetBool("status-stalled") && stalled > 0 {
+					os.Exit(1)
+				}
+				return
+			}
+			time.Sleep(watch)
+		}
+	},
+}
+
+// statusSource reads the stores and everything shown in their status
+type statusSource interface {
+	storeStateSource
+	ownedStores() ([]string, error)
+	subscriptions() ([]string, error)
+	mirrors(storeID string) ([]types.DatalayerMirror, error)
+}
+
+// rpcStatusSource reads the status of stores from the data layer RPC
+type rpcStatusSource struct {
+	rpcStoreState
+}
+
+func (s rpcStatusSource) ownedStores() ([]string, error) {
+	owned, _, err := s.client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return owned.StoreIDs, nil
+}
+
+func (s rpcStatusSource) subscriptions() ([]string, error) {
+	subscriptions, _, err := s.client.DataLayerService.GetSubscriptions(&rpc.DatalayerGetSubscriptionsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions.StoreIDs, nil
+}
+
+func (s rpcStatusSource) mirrors(storeID string) ([]types.DatalayerMirror, error) {
+	return rpcMirrorSource{client: s.client}.mirrors(storeID)
+}
+
+// collectStoreStatuses returns the status of every subscribed and owned store, sorted with owned stores first
+func collectStoreStatuses(source statusSource, concurrency int) ([]storeStatus, error) {
+	owned, err := source.ownedStores()
+	if err != nil {
+		return nil, fmt.Errorf("error getting list of owned data stores: %w", err)
+	}
+	subscriptions, err := source.subscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("error getting list of datalayer subscriptions: %w", err)
+	}
+
+	storeIDs := slices.Clone(owned)
+	for _, storeID := range subscriptions {
+		if !slices.Contains(storeIDs, storeID) {
+			storeIDs = append(storeIDs, storeID)
+		}
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	statuses := make([]storeStatus, len(storeIDs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				statuses[idx] = getStoreStatus(source, storeIDs[idx], slices.Contains(owned, storeIDs[idx]))
+			}
+		}()
+	}
+	for idx := range storeIDs {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Owned != statuses[j].Owned {
+			return statuses[i].Owned
+		}
+		return statuses[i].StoreID < statuses[j].StoreID
+	})
+	return statuses, nil
+}
+
+// getStoreStatus returns the status of a single store
+// Errors are recorded in the status so one broken store doesn't hide the rest
+func getStoreStatus(source statusSource, storeID string, owned bool) storeStatus {
+	status := storeStatus{StoreID: storeID, Owned: owned}
+
+	syncState, err := source.syncStatus(storeID)
+	if isStoreNotCreatedError(err) {
+		status.State = statePending
+		return status
+	}
+	if err != nil {
+		status.State = stateError
+		status.Error = err.Error()
+		return status
+	}
+	status.Generation = syncState.Generation
+	status.TargetGeneration = syncState.TargetGeneration
+	status.Stalled = syncState.Generation < syncState.TargetGeneration
+	status.State = stateSynced
+	if status.Stalled {
+		status.State = stateStalled
+	}
+
+	root, err := source.root(storeID)
+	if err != nil {
+		status.State = stateError
+		status.Error = err.Error()
+		return status
+	}
+	status.RootHash = root.Hash
+	status.Timestamp = root.Timestamp
+
+	mirrors, err := source.mirrors(storeID)
+	if err != nil {
+		status.State = stateError
+		status.Error = err.Error()
+		return status
+	}
+	status.Mirrors = len(mirrors)
+
+	return status
+}
+
+// filterStoreStatuses returns the statuses to show, only including stalled stores when onlyStalled is set, along with
+// the number of stalled stores and the number of stores that couldn't be read
+func filterStoreStatuses(statuses []storeStatus, onlyStalled bool) ([]storeStatus, int, int) {
+	stalled, errored := 0, 0
+	var shown []storeStatus
+	for _, status := range statuses {
+		if status.Stalled {
+			stalled++
+		}
+		if status.State == stateError {
+			errored++
+		}
+		if !onlyStalled || status.Stalled {
+			shown = append(shown, status)
+		}
+	}
+	return shown, stalled, errored
+}
+
+// printStoreStatuses writes the statuses as a table
+func printStoreStatuses(out io.Writer, statuses []storeStatus) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STORE\tTYPE\tGENERATION\tROOT HASH\tUPDATED\tMIRRORS\tSTATUS")
+	for _, status := range statuses {
+		storeType := "subscribed"
+		if status.Owned {
+			storeType = "owned"
+		}
+		state := status.State
+		if status.Error != "" {
+			state += ": " + status.Error
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%d\t%s\n",
+			status.StoreID,
+			storeType,
+			status.Generation,
+			status.TargetGeneration,
+			status.RootHash,
+			formatRootTimestamp(status.Timestamp),
+			status.Mirrors,
+			state)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	statusCmd.PersistentFlags().Duration("watch", 0, "Refresh the status on this interval, for example 30s")
+	statusCmd.PersistentFlags().Bool("stalled", false, "Only show stores that are behind their target generation, and exit 1 if there are any")
+	statusCmd.PersistentFlags().Bool("as-json", false, "Output the status as JSON")
+	statusCmd.PersistentFlags().Int("concurrency", 8, "Number of stores to check at the same time")
+
+	cobra.CheckErr(viper.BindPFlag("status-watch", statusCmd.PersistentFlags().Lookup("watch")))
+	cobra.CheckErr(viper.BindPFlag("status-stalled", statusCmd.PersistentFlags().Lookup("stalled")))
+	cobra.CheckErr(viper.BindPFlag("status-as-json", statusCmd.PersistentFlags().Lookup("as-json")))
+	cobra.CheckErr(viper.BindPFlag("status-concurrency", statusCmd.PersistentFlags().Lookup("concurrency")))
+
+	datalayerCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,73 @@
+package datalayer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStatusSource serves the stores and their state from memory
+type fakeStatusSource struct {
+	fakeStoreState
+	owned        []string
+	subscribed   []string
+	storeMirrors map[string][]types.DatalayerMirror
+}
+
+func (s fakeStatusSource) ownedStores() ([]string, error) {
+	return s.owned, nil
+}
+
+func (s fakeStatusSource) subscriptions() ([]string, error) {
+	return s.subscribed, nil
+}
+
+func (s fakeStatusSource) mirrors(storeID string) ([]types.DatalayerMirror, error) {
+	return s.storeMirrors[storeID], nil
+}
+
+func TestCollectStoreStatuses(t *testing.T) {
+	source := fakeStatusSource{
+		fakeStoreState: fakeStoreState{
+			statuses: map[string]*syncStatus{
+				"synced":  {Generation: 5, TargetGeneration: 5},
+				"behind":  {Generation: 3, TargetGeneration: 5},
+				"no-root": {Generation: 2, TargetGeneration: 2},
+			},
+			roots: map[string]*getRootResponse{
+				"synced": {Hash: "0xaa", Timestamp: 100},
+				"behind": {Hash: "0xbb", Timestamp: 200},
+			},
+			errs: map[string]error{
+				"not-created": errors.New("get_sync_status was not successful: No store id stored in the local database for not-created"),
+				"offline":     errors.New("error calling get_sync_status: connection refused"),
+			},
+		},
+		owned:      []string{"synced"},
+		subscribed: []string{"synced", "behind", "no-root", "not-created", "offline"},
+		storeMirrors: map[string][]types.DatalayerMirror{
+			"synced": {{}, {}},
+		},
+	}
+
+	statuses, err := collectStoreStatuses(source, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []storeStatus{
+		{StoreID: "synced", Owned: true, Generation: 5, TargetGeneration: 5, RootHash: "0xaa", Timestamp: 100, Mirrors: 2, State: stateSynced},
+		{StoreID: "behind", Generation: 3, TargetGeneration: 5, RootHash: "0xbb", Timestamp: 200, State: stateStalled, Stalled: true},
+		{StoreID: "no-root", Generation: 2, TargetGeneration: 2, State: stateError, Error: "get_root was not successful: Failed to get root for no-root"},
+		{StoreID: "not-created", State: statePending},
+		{StoreID: "offline", State: stateError, Error: "error calling get_sync_status: connection refused"},
+	}, statuses)
+
+	// Stores that couldn't be read or haven't received their first root aren't stalled
+	shown, stalled, errored := filterStoreStatuses(statuses, true)
+	assert.Equal(t, []storeStatus{statuses[1]}, shown)
+	assert.Equal(t, 1, stalled)
+	assert.Equal(t, 2, errored)
+
+	shown, _, _ = filterStoreStatuses(statuses, false)
+	assert.Equal(t, statuses, shown)
+}
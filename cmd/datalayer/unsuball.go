@@ -1,23 +1,51 @@
 package datalayer
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/chik-network/go-chik-libs/pkg/rpc"
 	"github.com/chik-network/go-modules/pkg/slogs"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
 )
 
 // unsubAllCmd Unsubscribes from all non-owned datalayer stores
 var unsubAllCmd = &cobra.Command{
 	Use:   "unsub-all",
 	Short: "Unsubscribes from all datalayer stores except for owned stores",
+	Example: `chik-tools data unsub-all
+
+# Keep some stores, listed by store ID or in a file with one ID per line
+chik-tools data unsub-all --exclude <store-id> --exclude keep-these.txt
+
+# Only unsubscribe from stores that are not synced, or have not had a new root in 30 days, and delete their data
+chik-tools data unsub-all --not-synced --older-than 720h --retain-data=false
+
+# Show what changes would be made without actually unsubscribing
+chik-tools data unsub-all --dry-run`,
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
 		if err != nil {
 			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
 		}
 
+		include, err := readIDList(viper.GetStringSlice("unsub-all-include"))
+		if err != nil {
+			slogs.Logr.Fatal("error reading include list", "error", err)
+		}
+		exclude, err := readIDList(viper.GetStringSlice("unsub-all-exclude"))
+		if err != nil {
+			slogs.Logr.Fatal("error reading exclude list", "error", err)
+		}
+
 		ownedStores, _, err := client.DataLayerService.GetOwnedStores(&rpc.DatalayerGetOwnedStoresOptions{})
 		if err != nil {
 			slogs.Logr.Fatal("error getting list of owned data stores", "error", err)
@@ -28,15 +56,65 @@ var unsubAllCmd = &cobra.Command{
 			slogs.Logr.Fatal("error getting list of datalayer subscriptions", "error", err)
 		}
 
+		notSynced := viper.GetBool("unsub-all-not-synced")
+		olderThan := viper.GetDuration("unsub-all-older-than")
+
+		var toRemove []string
 		for _, subscription := range subscriptions.StoreIDs {
 			if slices.Contains(ownedStores.StoreIDs, subscription) {
 				slogs.Logr.Info("Owned store found, skipping", "store", subscription)
 				continue
 			}
+			if len(include) > 0 && !slices.Contains(include, normalizeStoreID(subscription)) {
+				slogs.Logr.Debug("Store not in include list, skipping", "store", subscription)
+				continue
+			}
+			if slices.Contains(exclude, normalizeStoreID(subscription)) {
+				slogs.Logr.Info("Excluded store found, skipping", "store", subscription)
+				continue
+			}
+			if notSynced || olderThan > 0 {
+				matches, err := matchesSyncFilter(rpcStoreState{client: client}, subscription, notSynced, olderThan)
+				if err != nil {
+					slogs.Logr.Warn("error checking sync status for store, skipping", "store", subscription, "error", err)
+					continue
+				}
+				if !matches {
+					slogs.Logr.Debug("Store does not match sync filters, skipping", "store", subscription)
+					continue
+				}
+			}
+			toRemove = append(toRemove, subscription)
+		}
+
+		retainData := viper.GetBool("unsub-all-retain-data")
+		if viper.GetBool("dry-run") {
+			for _, subscription := range toRemove {
+				slogs.Logr.Info("DRY RUN: Would unsubscribe from store", "store", subscription, "retain_data", retainData)
+			}
+			slogs.Logr.Info("DRY RUN: No changes were made to subscriptions")
+			return
+		}
+
+		if len(toRemove) == 0 {
+			slogs.Logr.Info("No subscriptions to remove")
+			return
+		}
+
+		prompt := fmt.Sprintf("Unsubscribe from %d stores? (y/N)", len(toRemove))
+		if !retainData {
+			prompt = fmt.Sprintf("Unsubscribe from %d stores and delete their data? (y/N)", len(toRemove))
+		}
+		if !utils.ConfirmAction(prompt, viper.GetBool("unsub-all-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		for _, subscription := range toRemove {
 			slogs.Logr.Info("Unsubscribing from subscription", "store", subscription)
 			resp, _, err := client.DataLayerService.Unsubscribe(&rpc.DatalayerUnsubscribeOptions{
 				ID:         subscription,
-				RetainData: true,
+				RetainData: retainData,
 			})
 			if err != nil {
 				slogs.Logr.Fatal("error unsubscribing from store", "store", subscription, "error", err)
@@ -48,6 +126,104 @@ var unsubAllCmd = &cobra.Command{
 	},
 }
 
+// matchesSyncFilter returns true when the store is not synced, or its latest root is older than the given duration
+// When both filters are enabled, matching either one is enough. Stores that don't have a root yet match both filters
+func matchesSyncFilter(source storeStateSource, storeID string, notSynced bool, olderThan time.Duration) (bool, error) {
+	status, err := source.syncStatus(storeID)
+	if isStoreNotCreatedError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// Generation 0 is the empty root data layer creates when it starts syncing a store, so nothing has synced yet
+	if status.Generation == 0 {
+		return true, nil
+	}
+
+	if notSynced && status.Generation < status.TargetGeneration {
+		return true, nil
+	}
+
+	if olderThan > 0 {
+		root, err := source.root(storeID)
+		if err != nil {
+			return false, err
+		}
+		if time.Since(time.Unix(root.Timestamp, 0)) > olderThan {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// storeNotCreatedError is the error get_sync_status returns for a store that is subscribed to, but that data layer
+// hasn't created in its local database yet. It is raised by DataLayer.get_sync_status in chik/data_layer/data_layer.py
+// as "No store id stored in the local database for <store id>". There is no RPC that reports this without an error
+const storeNotCreatedError = "No store id stored in the local database"
+
+// isStoreNotCreatedError returns true when the error is data layer reporting that it has no local copy of the store
+func isStoreNotCreatedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), storeNotCreatedError)
+}
+
+// storeIDRegex matches a store ID, with or without the 0x prefix
+var storeIDRegex = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+
+// readIDList expands a list of store IDs, where any entry that is not a store ID is read as a file of IDs
+// Files contain one ID per line. Blank lines and lines starting with # are ignored
+// IDs are returned normalized, in lowercase without the 0x prefix
+func readIDList(entries []string) ([]string, error) {
+	var ids []string
+	for _, entry := range entries {
+		if storeIDRegex.MatchString(entry) {
+			ids = append(ids, normalizeStoreID(entry))
+			continue
+		}
+
+		file, err := os.Open(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a store ID or a readable file: %w", entry, err)
+		}
+		scanner := bufio.NewScanner(file)
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !storeIDRegex.MatchString(line) {
+				_ = file.Close()
+				return nil, fmt.Errorf("%s line %d: %s is not a store ID", entry, lineNumber, line)
+			}
+			ids = append(ids, normalizeStoreID(line))
+		}
+		err = scanner.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", entry, err)
+		}
+	}
+
+	return ids, nil
+}
+
 func init() {
+	unsubAllCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+	unsubAllCmd.PersistentFlags().Bool("retain-data", true, "Keep the local data for stores after unsubscribing")
+	unsubAllCmd.PersistentFlags().StringSlice("include", nil, "Only unsubscribe from these store IDs. Entries may also be files with one ID per line")
+	unsubAllCmd.PersistentFlags().StringSlice("exclude", nil, "Never unsubscribe from these store IDs. Entries may also be files with one ID per line")
+	unsubAllCmd.PersistentFlags().Bool("not-synced", false, "Only unsubscribe from stores that are behind their target generation")
+	unsubAllCmd.PersistentFlags().Duration("older-than", 0, "Only unsubscribe from stores whose latest local root is older than this duration, for example 720h")
+
+	cobra.CheckErr(viper.BindPFlag("unsub-all-yes", unsubAllCmd.PersistentFlags().Lookup("yes")))
+	cobra.CheckErr(viper.BindPFlag("unsub-all-retain-data", unsubAllCmd.PersistentFlags().Lookup("retain-data")))
+	cobra.CheckErr(viper.BindPFlag("unsub-all-include", unsubAllCmd.PersistentFlags().Lookup("include")))
+	cobra.CheckErr(viper.BindPFlag("unsub-all-exclude", unsubAllCmd.PersistentFlags().Lookup("exclude")))
+	cobra.CheckErr(viper.BindPFlag("unsub-all-not-synced", unsubAllCmd.PersistentFlags().Lookup("not-synced")))
+	cobra.CheckErr(viper.BindPFlag("unsub-all-older-than", unsubAllCmd.PersistentFlags().Lookup("older-than")))
+
 	datalayerCmd.AddCommand(unsubAllCmd)
 }
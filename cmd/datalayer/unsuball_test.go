@@ -0,0 +1,113 @@
+package datalayer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIDList(t *testing.T) {
+	first := strings.Repeat("aa", 32)
+	second := strings.Repeat("bb", 32)
+	third := strings.Repeat("cc", 32)
+
+	listFile := filepath.Join(t.TempDir(), "keep.txt")
+	err := os.WriteFile(listFile, []byte("# stores to keep\n\n0x"+strings.ToUpper(second)+"\n  "+third+"  \n"), 0644)
+	assert.NoError(t, err)
+
+	ids, err := readIDList([]string{first, listFile})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first, second, third}, ids)
+
+	// A typo in an ID must not silently become an ID that never matches
+	_, err = readIDList([]string{first[:63]})
+	assert.ErrorContains(t, err, "not a store ID or a readable file")
+
+	err = os.WriteFile(listFile, []byte(first+"\nabcd1234\n"), 0644)
+	assert.NoError(t, err)
+	_, err = readIDList([]string{listFile})
+	assert.ErrorContains(t, err, "line 2")
+}
+
+// TestIsStoreNotCreatedError pins the message chik's DataLayer.get_sync_status raises for a store it hasn't created
+// locally. If chik changes the wording, unsub-all --not-synced and --older-than skip those stores instead of matching them
+func TestIsStoreNotCreatedError(t *testing.T) {
+	storeID := strings.Repeat("aa", 32)
+	assert.False(t, isStoreNotCreatedError(nil))
+	assert.False(t, isStoreNotCreatedError(errors.New("error calling get_sync_status: connection refused")))
+	assert.True(t, isStoreNotCreatedError(errors.New("get_sync_status was not successful: No store id stored in the local database for "+storeID)))
+}
+
+// fakeStoreState serves the sync status and root of each store from memory
+type fakeStoreState struct {
+	statuses map[string]*syncStatus
+	roots    map[string]*getRootResponse
+	errs     map[string]error
+}
+
+func (s fakeStoreState) syncStatus(storeID string) (*syncStatus, error) {
+	if err := s.errs[storeID]; err != nil {
+		return nil, err
+	}
+	return s.statuses[storeID], nil
+}
+
+func (s fakeStoreState) root(storeID string) (*getRootResponse, error) {
+	if root, ok := s.roots[storeID]; ok {
+		return root, nil
+	}
+	return nil, errors.New("get_root was not successful: Failed to get root for " + storeID)
+}
+
+func TestMatchesSyncFilter(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).Unix()
+	old := time.Now().Add(-60 * 24 * time.Hour).Unix()
+	source := fakeStoreState{
+		statuses: map[string]*syncStatus{
+			"synced":   {Generation: 5, TargetGeneration: 5},
+			"behind":   {Generation: 3, TargetGeneration: 5},
+			"old":      {Generation: 5, TargetGeneration: 5},
+			"no-roots": {Generation: 0, TargetGeneration: 0},
+		},
+		roots: map[string]*getRootResponse{
+			"synced": {Timestamp: recent},
+			"behind": {Timestamp: recent},
+			"old":    {Timestamp: old},
+		},
+		errs: map[string]error{
+			"not-created": errors.New("get_sync_status was not successful: No store id stored in the local database for not-created"),
+			"offline":     errors.New("error calling get_sync_status: connection refused"),
+		},
+	}
+	month := 30 * 24 * time.Hour
+
+	for _, tt := range []struct {
+		storeID   string
+		notSynced bool
+		olderThan time.Duration
+		expected  bool
+	}{
+		{"synced", true, month, false},
+		{"behind", true, 0, true},
+		{"behind", false, month, false},
+		{"old", true, 0, false},
+		{"old", false, month, true},
+		{"no-roots", true, 0, true},
+		{"no-roots", false, month, true},
+		{"not-created", true, 0, true},
+		{"not-created", false, month, true},
+	} {
+		matches, err := matchesSyncFilter(source, tt.storeID, tt.notSynced, tt.olderThan)
+		assert.NoError(t, err, tt.storeID)
+		assert.Equal(t, tt.expected, matches, tt.storeID)
+	}
+
+	// Other errors are returned so the store is skipped rather than unsubscribed
+	_, err := matchesSyncFilter(source, "offline", true, month)
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+package datalayer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// upsertRow is a single row of the upsert input file
+// Action is upsert when empty. Value is ignored for deletes
+type upsertRow struct {
+	Action string          `json:"action"`
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// upsertCmd writes keys and values from a CSV or JSON file to an owned store
+var upsertCmd = &cobra.Command{
+	Use:   "upsert",
+	Short: "Inserts, updates and deletes keys in an owned store from a CSV or JSON file",
+	Long: `Inserts, updates and deletes keys in an owned store from a CSV or JSON file.
+
+CSV files need a header row with key and value columns, and an optional action column.
+JSON files are an array of objects with key, value and optional action fields, or a
+single object mapping keys to values. The action is upsert or delete, and defaults to upsert.
+
+Keys are encoded with --key-format and values with --value-format. With the json value
+format, values are stored as compact JSON.`,
+	Example: `chik-tools data upsert --id abcd1234 --file rows.csv -m 0.00001
+chik-tools data upsert --id abcd1234 --file rows.json --value-format json
+
+# Keys and values that are already hex
+chik-tools data upsert --id abcd1234 --file rows.csv --key-format hex --value-format hex
+
+# Show the batches that would be sent without making any changes
+chik-tools data upsert --id abcd1234 --file rows.csv --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		storeID := viper.GetString("upsert-id")
+		file := viper.GetString("upsert-file")
+		if storeID == "" || file == "" {
+			slogs.Logr.Fatal("--id and --file are required")
+		}
+
+		rows, err := readUpsertRows(file)
+		if err != nil {
+			slogs.Logr.Fatal("error reading input file", "file", file, "error", err)
+		}
+
+		batcher := &changelistBatcher{maxBytes: viper.GetInt("upsert-max-batch-bytes")}
+		var batches [][]changelistEntry
+		for idx, row := range rows {
+			entry, err := encodeUpsertRow(row, viper.GetString("upsert-key-format"), viper.GetString("upsert-value-format"))
+			if err != nil {
+				slogs.Logr.Fatal("invalid row", "row", idx+1, "error", err)
+			}
+			ready, err := batcher.add(entry)
+			if err != nil {
+				slogs.Logr.Fatal("invalid row", "row", idx+1, "error", err)
+			}
+			if ready != nil {
+				batches = append(batches, ready)
+			}
+		}
+		if remaining := batcher.flush(); remaining != nil {
+			batches = append(batches, remaining)
+		}
+		if len(batches) == 0 {
+			slogs.Logr.Info("No rows to write")
+			return
+		}
+
+		feeMojos, err := resolveFee(client, viper.GetString("upsert-fee"), viper.GetUint64("upsert-fee-target-time"))
+		if err != nil {
+			slogs.Logr.Fatal("error determining fee", "error", err)
+		}
+		logFeeBudget(len(batches), feeMojos, 0)
+
+		if viper.GetBool("dry-run") {
+			for idx, batch := range batches {
+				slogs.Logr.Info("DRY RUN: Would submit batch", "store", storeID, "batch", idx+1, "changes", len(batch))
+			}
+			slogs.Logr.Info("DRY RUN: No changes were made")
+			return
+		}
+
+		if !utils.ConfirmAction(fmt.Sprintf("Write %d changes to %s in %d batches? (y/N)", len(rows), storeID, len(batches)), viper.GetBool("upsert-yes")) {
+			slogs.Logr.Error("Cancelled")
+			return
+		}
+
+		root, err := getRoot(client, storeID)
+		if err != nil {
+			slogs.Logr.Fatal("error getting current root for store", "store", storeID, "error", err)
+		}
+		previousRoot := root.Hash
+		for idx, batch := range batches {
+			slogs.Logr.Info("Submitting batch", "store", storeID, "batch", idx+1, "of", len(batches), "changes", len(batch))
+			previousRoot, err = submitBatch(client, storeID, batch, feeMojos, previousRoot, viper.GetDuration("upsert-confirm-timeout"))
+			if err != nil {
+				slogs.Logr.Fatal("error writing batch. Earlier batches were confirmed", "store", storeID, "batch", idx+1, "error", err)
+			}
+			fmt.Printf("batch %d/%d confirmed: %d changes, root hash %s\n", idx+1, len(batches), len(batch), previousRoot)
+		}
+
+		slogs.Logr.Info("Finished writing to store", "store", storeID, "changes", len(rows), "root_hash", previousRoot)
+	},
+}
+
+// readUpsertRows reads the rows from a CSV or JSON file, based on the file extension
+func readUpsertRows(file string) ([]upsertRow, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return parseUpsertCSV(content)
+	case ".json":
+		return parseUpsertJSON(content)
+	default:
+		return nil, fmt.Errorf("unsupported file type %s. Must be .csv or .json", filepath.Ext(file))
+	}
+}
+
+// parseUpsertCSV parses CSV with a header row containing key, value and an optional action column
+func parseUpsertCSV(content []byte) ([]upsertRow, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header row: %w", err)
+	}
+	columns := map[string]int{}
+	for idx, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	keyCol, hasKey := columns["key"]
+	valueCol, hasValue := columns["value"]
+	actionCol, hasAction := columns["action"]
+	if !hasKey || !hasValue {
+		return nil, fmt.Errorf("header row must have key and value columns")
+	}
+
+	var rows []upsertRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := upsertRow{}
+		if keyCol < len(record) {
+			row.Key = record[keyCol]
+		}
+		if valueCol < len(record) {
+			// Store the text as a JSON string, so it is handled the same as JSON input
+			row.Value, _ = json.Marshal(record[valueCol])
+		}
+		if hasAction && actionCol < len(record) {
+			row.Action = record[actionCol]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseUpsertJSON parses a JSON array of rows, or an object mapping keys to values
+func parseUpsertJSON(content []byte) ([]upsertRow, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var kvs map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &kvs); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(kvs))
+		for key := range kvs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		rows := make([]upsertRow, 0, len(keys))
+		for _, key := range keys {
+			rows = append(rows, upsertRow{Key: key, Value: kvs[key]})
+		}
+		return rows, nil
+	}
+
+	var rows []upsertRow
+	if err := json.Unmarshal(trimmed, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// encodeUpsertRow encodes the key and value of a row into a hex changelist entry
+func encodeUpsertRow(row upsertRow, keyFormat, valueFormat string) (changelistEntry, error) {
+	action := strings.ToLower(strings.TrimSpace(row.Action))
+	if action == "" {
+		action = changeUpsert
+	}
+	if action != changeUpsert && action != changeDelete {
+		return changelistEntry{}, fmt.Errorf("unsupported action %s. Must be upsert or delete", row.Action)
+	}
+	if row.Key == "" {
+		return changelistEntry{}, fmt.Errorf("key can not be empty")
+	}
+
+	key, err := encodeUpsertText(row.Key, keyFormat)
+	if err != nil {
+		return changelistEntry{}, fmt.Errorf("key %s: %w", row.Key, err)
+	}
+	entry := changelistEntry{Action: action, Key: key}
+	if action == changeDelete {
+		return entry, nil
+	}
+
+	if valueFormat == formatJSON {
+		// JSON values are stored as compact JSON, even when the input had a JSON string containing JSON
+		var text string
+		if json.Unmarshal(row.Value, &text) == nil && json.Valid([]byte(text)) {
+			row.Value = json.RawMessage(text)
+		}
+		compact := &bytes.Buffer{}
+		if err := json.Compact(compact, row.Value); err != nil {
+			return changelistEntry{}, fmt.Errorf("value for key %s is not valid JSON: %w", row.Key, err)
+		}
+		entry.Value = hex.EncodeToString(compact.Bytes())
+		return entry, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(row.Value, &text); err != nil {
+		return changelistEntry{}, fmt.Errorf("value for key %s must be a string for the %s format", row.Key, valueFormat)
+	}
+	entry.Value, err = encodeUpsertText(text, valueFormat)
+	if err != nil {
+		return changelistEntry{}, fmt.Errorf("value for key %s: %w", row.Key, err)
+	}
+	return entry, nil
+}
+
+// encodeUpsertText converts text in the given format to the hex used in changelists
+func encodeUpsertText(text, format string) (string, error) {
+	switch format {
+	case formatUTF8:
+		return hex.EncodeToString([]byte(text)), nil
+	case formatHex:
+		decoded, err := hex.DecodeString(strings.TrimPrefix(text, "0x"))
+		if err != nil {
+			return "", fmt.Errorf("invalid hex: %w", err)
+		}
+		return hex.EncodeToString(decoded), nil
+	default:
+		return "", fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+func init() {
+	upsertCmd.PersistentFlags().String("id", "", "The owned store ID to write to (required)")
+	upsertCmd.PersistentFlags().StringP("file", "f", "", "CSV or JSON file with the rows to write (required)")
+	upsertCmd.PersistentFlags().String("key-format", formatUTF8, "Format of the keys in the file (utf8, hex)")
+	upsertCmd.PersistentFlags().String("value-format", formatUTF8, "Format of the values in the file (utf8, hex, json)")
+	upsertCmd.PersistentFlags().Int("max-batch-bytes", defaultMaxBatchBytes, "Maximum size in bytes of the decoded keys and values in each batch_update")
+	upsertCmd.PersistentFlags().Duration("confirm-timeout", defaultConfirmTimeout, "How long to wait for each batch to confirm before giving up. 0 waits forever")
+	upsertCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation")
+	addFeeFlags(upsertCmd, "Fee to use for each batch")
+
+	cobra.CheckErr(viper.BindPFlag("upsert-id", upsertCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("upsert-file", upsertCmd.PersistentFlags().Lookup("file")))
+	cobra.CheckErr(viper.BindPFlag("upsert-key-format", upsertCmd.PersistentFlags().Lookup("key-format")))
+	cobra.CheckErr(viper.BindPFlag("upsert-value-format", upsertCmd.PersistentFlags().Lookup("value-format")))
+	cobra.CheckErr(viper.BindPFlag("upsert-max-batch-bytes", upsertCmd.PersistentFlags().Lookup("max-batch-bytes")))
+	cobra.CheckErr(viper.BindPFlag("upsert-confirm-timeout", upsertCmd.PersistentFlags().Lookup("confirm-timeout")))
+	cobra.CheckErr(viper.BindPFlag("upsert-yes", upsertCmd.PersistentFlags().Lookup("yes")))
+	cobra.CheckErr(viper.BindPFlag("upsert-fee", upsertCmd.PersistentFlags().Lookup("fee")))
+	cobra.CheckErr(viper.BindPFlag("upsert-fee-target-time", upsertCmd.PersistentFlags().Lookup("fee-target-time")))
+
+	datalayerCmd.AddCommand(upsertCmd)
+}
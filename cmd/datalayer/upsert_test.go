@@ -0,0 +1,35 @@
+package datalayer
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeUpsertRows(t *testing.T) {
+	rows, err := parseUpsertCSV([]byte("key,value,action\nuser:1,alice,\nuser:2,,delete\nuser:3,\"{\"\"a\"\": 1}\",upsert\n"))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	entry, err := encodeUpsertRow(rows[0], formatUTF8, formatUTF8)
+	assert.NoError(t, err)
+	assert.Equal(t, changelistEntry{Action: changeUpsert, Key: hex.EncodeToString([]byte("user:1")), Value: hex.EncodeToString([]byte("alice"))}, entry)
+
+	entry, err = encodeUpsertRow(rows[1], formatUTF8, formatUTF8)
+	assert.NoError(t, err)
+	assert.Equal(t, changelistEntry{Action: changeDelete, Key: hex.EncodeToString([]byte("user:2"))}, entry)
+
+	entry, err = encodeUpsertRow(rows[2], formatUTF8, formatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString([]byte(`{"a":1}`)), entry.Value)
+
+	rows, err = parseUpsertJSON([]byte(`[{"key": "0x01", "value": {"b": [1, 2]}}]`))
+	assert.NoError(t, err)
+	entry, err = encodeUpsertRow(rows[0], formatHex, formatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, changelistEntry{Action: changeUpsert, Key: "01", Value: hex.EncodeToString([]byte(`{"b":[1,2]}`))}, entry)
+
+	_, err = encodeUpsertRow(upsertRow{Key: "k", Value: []byte(`"v"`), Action: "insert"}, formatUTF8, formatUTF8)
+	assert.Error(t, err)
+}
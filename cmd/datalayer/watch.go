@@ -0,0 +1,316 @@
+package datalayer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/rpc"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/chik-network/chik-tools/internal/utils"
+)
+
+// watchEvent is emitted for every new generation of a watched store
+type watchEvent struct {
+	StoreID          string       `json:"store_id"`
+	Generation       uint64       `json:"generation"`
+	RootHash         string       `json:"root_hash"`
+	PreviousRootHash string       `json:"previous_root_hash"`
+	Timestamp        int64        `json:"timestamp"`
+	Changes          []diffChange `json:"changes"`
+}
+
+// watchCheckpoint records the last generation delivered for each store
+type watchCheckpoint struct {
+	Stores map[string]uint64 `json:"stores"`
+}
+
+// watchCmd emits an event with the key changes for every new generation of the stores
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Follows stores and emits the key changes in every new generation as NDJSON or to a webhook",
+	Long: `Follows stores and emits the key changes in every new generation as NDJSON or to a webhook.
+
+The root history of each store is polled for new confirmed generations. Each generation is
+delivered at least once. The last delivered generation for each store is saved to the
+checkpoint file after delivery, so restarting the command continues where it left off.
+Stores that aren't in the checkpoint file start from their current generation.
+
+Without a webhook, events are written to stdout and logs are written to stderr. Failed webhook
+requests are retried, except for 4xx responses other than 408 and 429, which stop the command.`,
+	Example: `chik-tools data watch --id abcd1234 --id ef567890
+
+# Decode values as JSON and post each event to a webhook
+chik-tools data watch --id abcd1234 --output-format json --webhook https://indexer.example.com/events`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig())
+		if err != nil {
+			slogs.Logr.Fatal("error creating chik RPC client", "error", err)
+		}
+
+		storeIDs := viper.GetStringSlice("watch-id")
+		if len(storeIDs) == 0 {
+			slogs.Logr.Fatal("at least one store ID is required")
+		}
+
+		format, err := newKeyValueFormat(viper.GetString("watch-input-format"), viper.GetString("watch-output-format"), viper.GetString("watch-prefix"), viper.GetBool("watch-strict"))
+		if err != nil {
+			slogs.Logr.Fatal("invalid conversion options", "error", err)
+		}
+
+		checkpointFile := viper.GetString("watch-checkpoint")
+		checkpoint, err := loadWatchCheckpoint(checkpointFile)
+		if err != nil {
+			slogs.Logr.Fatal("error reading checkpoint file", "file", checkpointFile, "error", err)
+		}
+
+		deliver := func(event watchEvent) error {
+			if err := json.NewEncoder(os.Stdout).Encode(event); err != nil {
+				return permanentError{err}
+			}
+			return nil
+		}
+		if webhook := viper.GetString("watch-webhook"); webhook != "" {
+			headers, err := parseWebhookHeaders(viper.GetStringSlice("watch-webhook-header"))
+			if err != nil {
+				slogs.Logr.Fatal("invalid webhook header", "error", err)
+			}
+			httpClient := &http.Client{Timeout: viper.GetDuration("watch-webhook-timeout")}
+			deliver = func(event watchEvent) error {
+				return postWatchEvent(httpClient, webhook, headers, event)
+			}
+		} else {
+			// Events are written to stdout, so keep the logs out of the NDJSON stream
+			utils.LogToStderr()
+		}
+
+		source := rpcWatchSource{rpcDiffSource: rpcDiffSource{client: client, pageSize: defaultPageSize}}
+		interval := viper.GetDuration("watch-interval")
+		for {
+			for _, storeID := range storeIDs {
+				err = watchStore(source, storeID, checkpoint, format, deliver, func() error {
+					return saveWatchCheckpoint(checkpointFile, checkpoint)
+				})
+				var permanent permanentError
+				if errors.As(err, &permanent) {
+					slogs.Logr.Fatal("unable to deliver the next generation of store", "store", storeID, "error", err)
+				}
+				if err != nil {
+					slogs.Logr.Error("error checking store for new generations", "store", storeID, "error", err)
+				}
+			}
+			time.Sleep(interval)
+		}
+	},
+}
+
+// watchSource reads the root history of a store along with the keys and values needed to diff its generations
+type watchSource interface {
+	diffSource
+	rootHistory(storeID string) ([]rootHistoryEntry, error)
+}
+
+// rpcWatchSource reads from the data layer RPC
+type rpcWatchSource struct {
+	rpcDiffSource
+}
+
+func (s rpcWatchSource) rootHistory(storeID string) ([]rootHistoryEntry, error) {
+	return getRootHistory(s.client, storeID)
+}
+
+// watchStore delivers an event for every confirmed generation after the checkpoint, saving the checkpoint after each
+func watchStore(source watchSource, storeID string, checkpoint *watchCheckpoint, format keyValueFormat, deliver func(watchEvent) error, save func() error) error {
+	history, err := source.rootHistory(storeID)
+	if err != nil {
+		return err
+	}
+
+	// Only confirmed generations are delivered, so events are never sent for a root that could still change
+	latest := latestConfirmedGeneration(history)
+	if latest < 0 {
+		return nil
+	}
+
+	last, ok := checkpoint.Stores[storeID]
+	if !ok {
+		slogs.Logr.Info("Starting to watch store from its current generation", "store", storeID, "generation", latest)
+		checkpoint.Stores[storeID] = uint64(latest)
+		return save()
+	}
+
+	for generation := last + 1; generation <= uint64(latest); generation++ {
+		from := historyRef(history, generation-1)
+		to := historyRef(history, generation)
+		diff, err := computeGenerationDiff(source, storeID, from, to, format)
+		if err != nil {
+			return fmt.Errorf("error computing diff for generation %d: %w", generation, err)
+		}
+
+		event := watchEvent{
+			StoreID:          storeID,
+			Generation:       generation,
+			RootHash:         to.RootHash,
+			PreviousRootHash: from.RootHash,
+			Timestamp:        to.Timestamp,
+			Changes:          diff.Changes,
+		}
+		err = deliverWithRetry(deliver, event)
+		if err != nil {
+			return err
+		}
+
+		checkpoint.Stores[storeID] = generation
+		if err = save(); err != nil {
+			return fmt.Errorf("error saving checkpoint: %w", err)
+		}
+		slogs.Logr.Debug("Delivered generation", "store", storeID, "generation", generation, "changes", len(diff.Changes))
+	}
+	return nil
+}
+
+// deliverWithRetry retries delivery with exponential backoff until it succeeds or fails permanently
+func deliverWithRetry(deliver func(watchEvent) error, event watchEvent) error {
+	backoff := time.Second
+	for {
+		err := deliver(event)
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return err
+		}
+		slogs.Logr.Warn("error delivering event. Retrying", "store", event.StoreID, "generation", event.Generation, "sleep", backoff, "error", err)
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// parseWebhookHeaders parses the webhook headers, which must be in the form Name: value
+func parseWebhookHeaders(headers []string) (http.Header, error) {
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid webhook header %q. Must be Name: value", header)
+		}
+		parsed.Set(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// postWatchEvent posts the event as JSON to the webhook. Any non 2xx response is an error
+// 4xx responses other than 408 and 429 are permanent errors, since sending the same event again will be rejected the same way
+func postWatchEvent(httpClient *http.Client, webhook string, headers http.Header, event watchEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return permanentError{err}
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode <= 499 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return permanentError{fmt.Errorf("webhook returned %s", resp.Status)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// loadWatchCheckpoint reads the checkpoint file. A missing file is an empty checkpoint
+func loadWatchCheckpoint(file string) (*watchCheckpoint, error) {
+	checkpoint := &watchCheckpoint{Stores: map[string]uint64{}}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return checkpoint, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(content, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Stores == nil {
+		checkpoint.Stores = map[string]uint64{}
+	}
+	return checkpoint, nil
+}
+
+// saveWatchCheckpoint writes the checkpoint to a temporary file and renames it, so a crash never leaves a partial file
+func saveWatchCheckpoint(file string, checkpoint *watchCheckpoint) error {
+	content, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func init() {
+	watchCmd.PersistentFlags().StringSlice("id", nil, "Store ID to watch. Can be used more than once")
+	watchCmd.PersistentFlags().Duration("interval", 30*time.Second, "How often to check the stores for new generations")
+	watchCmd.PersistentFlags().String("checkpoint", "data-watch-checkpoint.json", "File that records the last generation delivered for each store")
+	watchCmd.PersistentFlags().String("webhook", "", "POST each event as JSON to this URL instead of writing it to stdout")
+	watchCmd.PersistentFlags().StringSlice("webhook-header", nil, "Extra header to send to the webhook, as Name: value. Can be used more than once")
+	watchCmd.PersistentFlags().Duration("webhook-timeout", 30*time.Second, "Timeout for each webhook request")
+	watchCmd.PersistentFlags().String("input-format", "hex", "Input format (hex, rawhex, utf8, base64)")
+	watchCmd.PersistentFlags().String("output-format", "utf8", "Output format (hex, rawhex, utf8, base64, json)")
+	watchCmd.PersistentFlags().String("prefix", "", "Only include keys that start with this prefix, after decoding the input format. Use 0x for a hex prefix")
+	watchCmd.PersistentFlags().Bool("strict", false, strictFlagUsage)
+
+	cobra.CheckErr(viper.BindPFlag("watch-id", watchCmd.PersistentFlags().Lookup("id")))
+	cobra.CheckErr(viper.BindPFlag("watch-interval", watchCmd.PersistentFlags().Lookup("interval")))
+	cobra.CheckErr(viper.BindPFlag("watch-checkpoint", watchCmd.PersistentFlags().Lookup("checkpoint")))
+	cobra.CheckErr(viper.BindPFlag("watch-webhook", watchCmd.PersistentFlags().Lookup("webhook")))
+	cobra.CheckErr(viper.BindPFlag("watch-webhook-header", watchCmd.PersistentFlags().Lookup("webhook-header")))
+	cobra.CheckErr(viper.BindPFlag("watch-webhook-timeout", watchCmd.PersistentFlags().Lookup("webhook-timeout")))
+	cobra.CheckErr(viper.BindPFlag("watch-input-format", watchCmd.PersistentFlags().Lookup("input-format")))
+	cobra.CheckErr(viper.BindPFlag("watch-output-format", watchCmd.PersistentFlags().Lookup("output-format")))
+	cobra.CheckErr(viper.BindPFlag("watch-prefix", watchCmd.PersistentFlags().Lookup("prefix")))
+	cobra.CheckErr(viper.BindPFlag("watch-strict", watchCmd.PersistentFlags().Lookup("strict")))
+
+	datalayerCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,183 @@
+package datalayer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chik-network/chik-tools/cmd"
+)
+
+func TestWatchCheckpoint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	checkpoint, err := loadWatchCheckpoint(file)
+	assert.NoError(t, err)
+	assert.Empty(t, checkpoint.Stores)
+
+	checkpoint.Stores["abcd"] = 3
+	assert.NoError(t, saveWatchCheckpoint(file, checkpoint))
+	loaded, err := loadWatchCheckpoint(file)
+	assert.NoError(t, err)
+	assert.Equal(t, checkpoint, loaded)
+
+	temps, err := filepath.Glob(file + ".tmp*")
+	assert.NoError(t, err)
+	assert.Empty(t, temps)
+
+	assert.NoError(t, os.WriteFile(file, []byte(`{"stores":null}`), 0644))
+	loaded, err = loadWatchCheckpoint(file)
+	assert.NoError(t, err)
+	assert.NotNil(t, loaded.Stores)
+
+	assert.NoError(t, os.WriteFile(file, []byte(`{"stores":`), 0644))
+	_, err = loadWatchCheckpoint(file)
+	assert.Error(t, err)
+}
+
+// fakeWatchSource serves the root history and keys of a store from memory
+type fakeWatchSource struct {
+	fakeDiffSource
+	history []rootHistoryEntry
+}
+
+func (s *fakeWatchSource) rootHistory(storeID string) ([]rootHistoryEntry, error) {
+	return s.history, nil
+}
+
+func TestWatchStore(t *testing.T) {
+	cmd.InitLogs()
+
+	empty := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	first := "0xaaaa000000000000000000000000000000000000000000000000000000000000"
+	second := "0xbbbb000000000000000000000000000000000000000000000000000000000000"
+	source := &fakeWatchSource{
+		fakeDiffSource: fakeDiffSource{
+			roots: map[string][]types.DatalayerKeyValue{
+				first:  {{Key: types.Bytes("a"), Value: types.Bytes("1")}},
+				second: {{Key: types.Bytes("a"), Value: types.Bytes("2")}},
+			},
+			diffs: map[[2]string][]kvDiffEntry{
+				{first, second}: {{Type: diffDelete, Key: types.Bytes("a"), Value: types.Bytes("1")}, {Type: diffInsert, Key: types.Bytes("a"), Value: types.Bytes("2")}},
+			},
+		},
+		history: []rootHistoryEntry{
+			{RootHash: empty, Confirmed: true},
+			{RootHash: first, Confirmed: true, Timestamp: 100},
+		},
+	}
+	format := keyValueFormat{InputFormat: formatHex, OutputFormat: formatUTF8}
+	checkpoint := &watchCheckpoint{Stores: map[string]uint64{}}
+	saves := 0
+	save := func() error {
+		saves++
+		return nil
+	}
+	var events []watchEvent
+	deliver := func(event watchEvent) error {
+		events = append(events, event)
+		return nil
+	}
+
+	// A new store starts from its current generation without delivering the existing history
+	assert.NoError(t, watchStore(source, "abcd", checkpoint, format, deliver, save))
+	assert.Empty(t, events)
+	assert.Equal(t, uint64(1), checkpoint.Stores["abcd"])
+	assert.Equal(t, 1, saves)
+
+	// Unconfirmed generations wait until they are confirmed
+	source.history = append(source.history, rootHistoryEntry{RootHash: second, Confirmed: false, Timestamp: 200})
+	assert.NoError(t, watchStore(source, "abcd", checkpoint, format, deliver, save))
+	assert.Empty(t, events)
+
+	// Each confirmed generation is delivered in order, including one that deletes every key
+	source.history[2].Confirmed = true
+	source.history = append(source.history, rootHistoryEntry{RootHash: empty, Confirmed: true, Timestamp: 300})
+	assert.NoError(t, watchStore(source, "abcd", checkpoint, format, deliver, save))
+	assert.Equal(t, []watchEvent{
+		{
+			StoreID: "abcd", Generation: 2, RootHash: second, PreviousRootHash: first, Timestamp: 200,
+			Changes: []diffChange{{Type: "delete", Key: "a", Value: "1"}, {Type: "insert", Key: "a", Value: "2"}},
+		},
+		{
+			StoreID: "abcd", Generation: 3, RootHash: empty, PreviousRootHash: second, Timestamp: 300,
+			Changes: []diffChange{{Type: "delete", Key: "a", Value: "2"}},
+		},
+	}, events)
+	assert.Equal(t, uint64(3), checkpoint.Stores["abcd"])
+	assert.Equal(t, 3, saves)
+
+	// A permanent delivery failure stops without advancing the checkpoint
+	source.history = append(source.history, rootHistoryEntry{RootHash: first, Confirmed: true, Timestamp: 400})
+	err := watchStore(source, "abcd", checkpoint, format, func(event watchEvent) error {
+		return permanentError{errors.New("webhook returned 400 Bad Request")}
+	}, save)
+	var permanent permanentError
+	assert.ErrorAs(t, err, &permanent)
+	assert.Equal(t, uint64(3), checkpoint.Stores["abcd"])
+
+	// A binary value is delivered as hex instead of stopping the watcher on that generation
+	binary := "0xcccc000000000000000000000000000000000000000000000000000000000000"
+	source.roots[binary] = []types.DatalayerKeyValue{{Key: types.Bytes("b"), Value: types.Bytes{0xff, 0x00}}}
+	source.history = append(source.history[:4], rootHistoryEntry{RootHash: binary, Confirmed: true, Timestamp: 500})
+	checkpoint.Stores["abcd"] = 3
+	events = nil
+	assert.NoError(t, watchStore(source, "abcd", checkpoint, format, deliver, save))
+	assert.Equal(t, []diffChange{{Type: "insert", Key: "b", Value: "0xff00"}}, events[0].Changes)
+	assert.Equal(t, uint64(4), checkpoint.Stores["abcd"])
+
+	// In strict mode the value can never be converted, so the error is permanent
+	format.Strict = true
+	checkpoint.Stores["abcd"] = 3
+	err = watchStore(source, "abcd", checkpoint, format, deliver, save)
+	assert.ErrorAs(t, err, &permanent)
+	assert.Equal(t, uint64(3), checkpoint.Stores["abcd"])
+}
+
+func TestParseWebhookHeaders(t *testing.T) {
+	headers, err := parseWebhookHeaders([]string{"Authorization: Bearer abc:123", "x-source:chik"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc:123", headers.Get("Authorization"))
+	assert.Equal(t, "chik", headers.Get("X-Source"))
+
+	for _, invalid := range []string{"Authorization", ": value"} {
+		_, err = parseWebhookHeaders([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPostWatchEvent(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer abc")
+	event := watchEvent{StoreID: "abcd", Generation: 1, Changes: []diffChange{}}
+	var permanent permanentError
+
+	assert.NoError(t, postWatchEvent(server.Client(), server.URL, headers, event))
+
+	for _, retryable := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway} {
+		status = retryable
+		err := postWatchEvent(server.Client(), server.URL, headers, event)
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &permanent), retryable)
+	}
+
+	status = http.StatusUnauthorized
+	err := postWatchEvent(server.Client(), server.URL, headers, event)
+	assert.ErrorAs(t, err, &permanent)
+	assert.NoError(t, deliverWithRetry(func(watchEvent) error { return nil }, event))
+	assert.ErrorAs(t, deliverWithRetry(func(watchEvent) error { return err }, event), &permanent)
+}
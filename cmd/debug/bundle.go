@@ -0,0 +1,284 @@
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chik-network/chik-tools/cmd"
+	"github.com/chik-network/chik-tools/cmd/certs"
+	"github.com/chik-network/chik-tools/cmd/network"
+)
+
+// redactedKeys matches config keys whose values identify the user or are secret, and are removed from the bundle
+var redactedKeys = regexp.MustCompile(`(?i)(password|passphrase|secret|token|api_key|_address$|_public_keys?$|puzzle_hash$|payout_instructions$|launcher_id$)`)
+
+// redactedValue replaces the value of redacted config keys
+const redactedValue = "REDACTED"
+
+// bundleFile is a single file in the bundle archive
+type bundleFile struct {
+	Name    string
+	Content []byte
+}
+
+// bundleManifestEntry is the checksum of a file in the bundle
+type bundleManifestEntry struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// bundleManifest describes the contents of the bundle
+type bundleManifest struct {
+	CreatedAt        time.Time             `json:"created_at"`
+	ChikToolsVersion string                `json:"chik_tools_version"`
+	ChikRoot         string                `json:"chik_root"`
+	Files            []bundleManifestEntry `json:"files"`
+	Errors           map[string]string     `json:"errors,omitempty"`
+}
+
+// bundleCmd writes the debug information to an archive that can be attached to support tickets
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Writes debug information, a redacted config and recent logs to an archive for support tickets",
+	Long: `Writes debug information, a redacted config and recent logs to an archive for support tickets.
+
+The archive contains every debug section in debug.json, the networks in the config, the expiry
+of every certificate in the config, config.yaml with addresses, keys and secrets redacted, and
+the end of each debug.log. manifest.json lists the SHA256 checksum of every file. Sections that
+can't be collected are listed in the manifest errors instead of stopping the bundle.`,
+	Example: `chik-tools debug bundle -o chik-debug.tar.gz
+
+# Include up to 50MB of each debug.log
+chik-tools debug bundle -o chik-debug.tar.gz --log-size 50`,
+	Run: func(cmd *cobra.Command, args []string) {
+		chikRoot, err := config.GetChikRootPath()
+		if err != nil {
+			slogs.Logr.Fatal("error determining chik root", "error", err)
+		}
+
+		files, errs := collectBundleFiles(chikRoot, viper.GetInt64("bundle-log-size")*1024*1024)
+		for section, sectionErr := range errs {
+			slogs.Logr.Warn("error collecting section", "section", section, "error", sectionErr)
+		}
+
+		output := viper.GetString("bundle-output")
+		err = writeBundle(output, chikRoot, files, errs)
+		if err != nil {
+			slogs.Logr.Fatal("error writing bundle", "file", output, "error", err)
+		}
+		slogs.Logr.Info("Wrote debug bundle", "file", output, "files", len(files))
+	},
+}
+
+// collectBundleFiles gathers every file for the bundle. Errors are returned per section so one failure doesn't
+// prevent the rest of the bundle from being written
+func collectBundleFiles(chikRoot string, logSize int64) ([]bundleFile, map[string]string) {
+	var files []bundleFile
+	errs := map[string]string{}
+	addJSON := func(name string, collect func() (any, error)) {
+		data, err := collect()
+		if err == nil {
+			var content []byte
+			content, err = json.MarshalIndent(data, "", "  ")
+			if err == nil {
+				files = append(files, bundleFile{Name: name, Content: content})
+				return
+			}
+		}
+		errs[name] = err.Error()
+	}
+
+	addJSON("debug.json", func() (any, error) {
+		sections, _ := parseSections(nil)
+		report := collectDebugReport(sections)
+		for section, sectionErr := range report.Errors {
+			errs["debug.json "+section] = sectionErr
+		}
+		return report, nil
+	})
+	addJSON("networks.json", func() (any, error) { return network.ListNetworks() })
+	addJSON("certificates.json", func() (any, error) {
+		return certs.ConfigCertificateStatus(30*24*time.Hour, 7*24*time.Hour)
+	})
+
+	cfg, err := os.ReadFile(filepath.Join(chikRoot, "config", "config.yaml"))
+	if err == nil {
+		cfg, err = redactConfig(cfg)
+	}
+	if err != nil {
+		errs["config.yaml"] = err.Error()
+	} else {
+		files = append(files, bundleFile{Name: "config.yaml", Content: cfg})
+	}
+
+	logs, err := filepath.Glob(filepath.Join(chikRoot, "log", "debug.log*"))
+	if err != nil {
+		errs["logs"] = err.Error()
+	}
+	for _, logPath := range logs {
+		name := filepath.Join("log", filepath.Base(logPath))
+		content, err := tailFile(logPath, logSize)
+		if err != nil {
+			errs[name] = err.Error()
+			continue
+		}
+		files = append(files, bundleFile{Name: name, Content: content})
+	}
+
+	return files, errs
+}
+
+// redactConfig replaces the values of sensitive keys in the config, keeping comments and layout
+func redactConfig(content []byte) ([]byte, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	redactNode(&doc)
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// redactNode walks the yaml tree, redacting every scalar under a sensitive key
+func redactNode(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if redactedKeys.MatchString(node.Content[i].Value) {
+				redactValue(node.Content[i+1])
+				continue
+			}
+			redactNode(node.Content[i+1])
+		}
+		return
+	}
+	for _, child := range node.Content {
+		redactNode(child)
+	}
+}
+
+// redactValue replaces every non-empty scalar in the node
+func redactValue(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value != "" && node.Tag != "!!null" {
+			node.Value = redactedValue
+			node.Tag = "!!str"
+			node.Style = 0
+		}
+		return
+	}
+	for _, child := range node.Content {
+		redactValue(child)
+	}
+}
+
+// tailFile returns at most the last maxBytes of the file
+func tailFile(path string, maxBytes int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxBytes {
+		_, err = f.Seek(info.Size()-maxBytes, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// writeBundle writes the files and a manifest with their checksums to a gzipped tar archive
+func writeBundle(output, chikRoot string, files []bundleFile, errs map[string]string) error {
+	manifest := bundleManifest{
+		CreatedAt:        time.Now().UTC(),
+		ChikToolsVersion: cmd.RootCmd.Version,
+		ChikRoot:         chikRoot,
+	}
+	if len(errs) > 0 {
+		manifest.Errors = errs
+	}
+	for _, file := range files {
+		sum := sha256.Sum256(file.Content)
+		manifest.Files = append(manifest.Files, bundleManifestEntry{
+			Name:   filepath.ToSlash(file.Name),
+			Size:   len(file.Content),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files = append(files, bundleFile{Name: "manifest.json", Content: manifestContent})
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    filepath.ToSlash(filepath.Join("chik-debug", file.Name)),
+			Mode:    0600,
+			Size:    int64(len(file.Content)),
+			ModTime: manifest.CreatedAt,
+		})
+		if err == nil {
+			_, err = tw.Write(file.Content)
+		}
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	bundleCmd.PersistentFlags().StringP("output", "o", "chik-debug.tar.gz", "The archive to write")
+	bundleCmd.PersistentFlags().Int64("log-size", 10, "Include at most this many MB from the end of each debug.log")
+
+	cobra.CheckErr(viper.BindPFlag("bundle-output", bundleCmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("bundle-log-size", bundleCmd.PersistentFlags().Lookup("log-size")))
+
+	debugCmd.AddCommand(bundleCmd)
+}
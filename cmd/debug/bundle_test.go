@@ -0,0 +1,51 @@
+package debug
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactConfig(t *testing.T) {
+	cfg := []byte(`farmer:
+  xck_target_address: xck1abc
+  pool_public_keys:
+    - 0xabc
+    - 0xdef
+  ssl:
+    public_key: config/ssl/farmer/public_farmer.key
+pool:
+  xck_target_address: xck1abc
+  launcher_id: null
+selected_network: mainnet
+`)
+	redacted, err := redactConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, `farmer:
+  xck_target_address: REDACTED
+  pool_public_keys:
+    - REDACTED
+    - REDACTED
+  ssl:
+    public_key: config/ssl/farmer/public_farmer.key
+pool:
+  xck_target_address: REDACTED
+  launcher_id: null
+selected_network: mainnet
+`, string(redacted))
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789"), 0644))
+
+	content, err := tailFile(path, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "6789", string(content))
+
+	content, err = tailFile(path, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(content))
+}
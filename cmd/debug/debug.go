@@ -1,11 +1,13 @@
 package debug
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -15,9 +17,11 @@ import (
 	"github.com/chik-network/go-modules/pkg/slogs"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 
 	"github.com/chik-network/chik-tools/cmd"
 	"github.com/chik-network/chik-tools/cmd/network"
+	"github.com/chik-network/chik-tools/internal/utils"
 )
 
 // Define a fixed column width for size
@@ -25,8 +29,8 @@ const sizeColumnWidth = 14
 
 // FileInfo stores file path and size
 type FileInfo struct {
-	Size int64
-	Path string
+	Size int64  `json:"size" yaml:"size"`
+	Path string `json:"path" yaml:"path"`
 }
 
 // Exclusions - List of patterns to exclude in the default mode
@@ -37,100 +41,233 @@ var exclusions = []string{
 	`run/.*`,
 }
 
+// Debug sections, in the order they are shown
+const (
+	sectionVersion = "version"
+	sectionNetwork = "network"
+	sectionPorts   = "ports"
+	sectionRPC     = "rpc"
+	sectionFiles   = "files"
+)
+
+var allSections = []string{sectionVersion, sectionNetwork, sectionPorts, sectionRPC, sectionFiles}
+
+// Output formats for the debug report
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// debugReport is the combined result of the debug sections
+// Sections that weren't requested are nil. Sections that failed are listed in Errors
+type debugReport struct {
+	Version VersionInfo          `json:"version,omitempty" yaml:"version,omitempty"`
+	Network *network.NetworkInfo `json:"network,omitempty" yaml:"network,omitempty"`
+	Ports   PortInfo             `json:"ports,omitempty" yaml:"ports,omitempty"`
+	RPC     RPCStatus            `json:"rpc,omitempty" yaml:"rpc,omitempty"`
+	Files   *FileSizes           `json:"files,omitempty" yaml:"files,omitempty"`
+	Errors  map[string]string    `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
 // debugCmd represents the config command
 var debugCmd = &cobra.Command{
 	Use:   "debug",
 	Short: "Outputs debugging information about Chik",
+	Example: `chik-tools debug
+
+# Only the port and RPC sections, as JSON
+chik-tools debug --output json --sections ports,rpc`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("# Version Information")
-		fmt.Println(strings.Repeat("-", 60)) // Separator
-		ShowVersionInfo()
+		output := viper.GetString("debug-output")
+		if output != outputTable && output != outputJSON && output != outputYAML {
+			slogs.Logr.Fatal("invalid output format. Must be table, json or yaml", "output", output)
+		}
+		sections, err := parseSections(viper.GetStringSlice("debug-sections"))
+		if err != nil {
+			slogs.Logr.Fatal("invalid sections", "error", err)
+		}
+		if output != outputTable {
+			// Keep the logs out of the document written to stdout. Section errors are also in the report
+			utils.LogToStderr()
+		}
 
-		fmt.Println("\n# Network Information")
-		fmt.Println(strings.Repeat("-", 60)) // Separator
-		network.ShowNetworkInfo()
+		report := collectDebugReport(sections)
+		for section, sectionErr := range report.Errors {
+			slogs.Logr.Error("error collecting section", "section", section, "error", sectionErr)
+		}
+
+		switch output {
+		case outputJSON:
+			content, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				slogs.Logr.Fatal("error marshalling report", "error", err)
+			}
+			fmt.Println(string(content))
+		case outputYAML:
+			content, err := yaml.Marshal(report)
+			if err != nil {
+				slogs.Logr.Fatal("error marshalling report", "error", err)
+			}
+			fmt.Print(string(content))
+		default:
+			writeReportTable(os.Stdout, report)
+			if report.Ports.hasPortConflicts() {
+				slogs.Logr.Warn("port conflicts found. Check the port information for services sharing a port or ports held by other processes")
+			}
+		}
+	},
+}
 
-		fmt.Println("\n# Port Information")
-		fmt.Println(strings.Repeat("-", 60)) // Separator
-		debugPorts()
+// parseSections validates the requested sections. No sections means all sections
+func parseSections(requested []string) (map[string]bool, error) {
+	sections := map[string]bool{}
+	if len(requested) == 0 {
+		requested = allSections
+	}
+	for _, section := range requested {
+		section = strings.ToLower(strings.TrimSpace(section))
+		if !slices.Contains(allSections, section) {
+			return nil, fmt.Errorf("unknown section %s. Must be one of %s", section, strings.Join(allSections, ", "))
+		}
+		sections[section] = true
+	}
+	return sections, nil
+}
 
-		fmt.Println("\n# RPC Server Status")
-		fmt.Println(strings.Repeat("-", 60)) // Separator
-		debugRPC()
+// collectDebugReport collects each requested section, recording errors instead of stopping at the first one
+func collectDebugReport(sections map[string]bool) *debugReport {
+	report := &debugReport{}
+	addError := func(section string, err error) {
+		if report.Errors == nil {
+			report.Errors = map[string]string{}
+		}
+		report.Errors[section] = err.Error()
+	}
 
-		fmt.Println("\n# File Sizes")
-		debugFileSizes()
-	},
+	var err error
+	if sections[sectionVersion] {
+		if report.Version, err = ShowVersionInfo(); err != nil {
+			addError(sectionVersion, err)
+		}
+	}
+	if sections[sectionNetwork] {
+		if report.Network, err = network.ShowNetworkInfo(); err != nil {
+			addError(sectionNetwork, err)
+		}
+	}
+	if sections[sectionPorts] {
+		if report.Ports, err = debugPorts(); err != nil {
+			addError(sectionPorts, err)
+		}
+	}
+	if sections[sectionRPC] {
+		if report.RPC, err = debugRPC(); err != nil {
+			addError(sectionRPC, err)
+		}
+	}
+	if sections[sectionFiles] {
+		if report.Files, err = debugFileSizes(); err != nil {
+			addError(sectionFiles, err)
+		}
+	}
+	return report
 }
 
-func debugRPC() {
-	slogs.Logr.Debug("initializing websocket client")
-	websocketClient, err := rpc.NewClient(rpc.ConnectionModeWebsocket, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
-	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
+// writeReportTable writes each collected section with a heading
+func writeReportTable(out io.Writer, report *debugReport) {
+	first := true
+	heading := func(title string, separator bool) {
+		if !first {
+			_, _ = fmt.Fprintln(out)
+		}
+		first = false
+		_, _ = fmt.Fprintf(out, "# %s\n", title)
+		if separator {
+			_, _ = fmt.Fprintln(out, strings.Repeat("-", 60)) // Separator
+		}
 	}
-	slogs.Logr.Debug("initializing http client")
-	rpcClient, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
+
+	if report.Version != nil {
+		heading("Version Information", true)
+		report.Version.WriteTable(out)
+	}
+	if report.Network != nil {
+		heading("Network Information", true)
+		report.Network.WriteTable(out)
+	}
+	if report.Ports != nil {
+		heading("Port Information", true)
+		report.Ports.WriteTable(out)
+	}
+	if report.RPC != nil {
+		heading("RPC Server Status", true)
+		report.RPC.WriteTable(out)
+	}
+	if report.Files != nil {
+		// The file table has its own separator under the column headers
+		heading("File Sizes", false)
+		report.Files.WriteTable(out)
+	}
+}
+
+// ServiceStatus is whether a single service's RPC server is responding
+type ServiceStatus struct {
+	Service string `json:"service" yaml:"service"`
+	Running bool   `json:"running" yaml:"running"`
+}
+
+// RPCStatus is the status of every service's RPC server
+type RPCStatus []ServiceStatus
+
+func debugRPC() (RPCStatus, error) {
+	services, err := debugServices()
 	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
-	}
-
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	runningHelper(w, websocketClient.DaemonService, "Daemon")
-	runningHelper(w, rpcClient.FullNodeService, "Full Node")
-	runningHelper(w, rpcClient.WalletService, "Wallet")
-	runningHelper(w, rpcClient.FarmerService, "Farmer")
-	runningHelper(w, rpcClient.HarvesterService, "Harvester")
-	runningHelper(w, rpcClient.CrawlerService, "Crawler")
-	runningHelper(w, rpcClient.DataLayerService, "Data Layer")
-	runningHelper(w, rpcClient.TimelordService, "Timelord")
+		return nil, err
+	}
+
+	var status RPCStatus
+	for _, s := range services {
+		status = append(status, runningHelper(s.service, s.label))
+	}
+	return status, nil
+}
+
+// WriteTable writes the RPC status as a table
+func (r RPCStatus) WriteTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	for _, service := range r {
+		running := "Running"
+		if !service.Running {
+			running = "Not Running"
+		}
+		_, _ = fmt.Fprintln(w, service.Service, "\t", running)
+	}
 	_ = w.Flush()
 }
 
-func runningHelper(w io.Writer, service hasVersionInfo, label string) {
-	running := "Running"
+func runningHelper(service hasVersionInfo, label string) ServiceStatus {
 	_, _, err := service.GetVersion(&rpc.GetVersionOptions{})
 	if err != nil {
 		slogs.Logr.Debug("error getting RPC Status from daemon", "error", err)
-		running = "Not Running"
+		return ServiceStatus{Service: label}
 	}
-	_, _ = fmt.Fprintln(w, label, "\t", running)
+	return ServiceStatus{Service: label, Running: true}
 }
 
-func debugPorts() {
-	cfg, err := config.GetChikConfig()
-	if err != nil {
-		fmt.Println("Could not load config")
-		return
-	}
-
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	_, _ = fmt.Fprintln(w, "Full Node Port\t", cfg.FullNode.Port)
-	_, _ = fmt.Fprintln(w, "Full Node RPC\t", cfg.FullNode.RPCPort)
-	_, _ = fmt.Fprintln(w, "Wallet RPC\t", cfg.Wallet.RPCPort)
-	_, _ = fmt.Fprintln(w, "Farmer Port\t", cfg.Farmer.Port)
-	_, _ = fmt.Fprintln(w, "Farmer RPC\t", cfg.Farmer.RPCPort)
-	_, _ = fmt.Fprintln(w, "Harvester RPC\t", cfg.Harvester.RPCPort)
-	_, _ = fmt.Fprintln(w, "Crawler RPC\t", cfg.Seeder.CrawlerConfig.RPCPort)
-	_, _ = fmt.Fprintln(w, "Seeder Port\t", cfg.Seeder.Port)
-	_, _ = fmt.Fprintln(w, "Data Layer Host Port\t", cfg.DataLayer.HostPort)
-	_, _ = fmt.Fprintln(w, "Data Layer RPC\t", cfg.DataLayer.RPCPort)
-	_, _ = fmt.Fprintln(w, "Timelord RPC\t", cfg.Timelord.RPCPort)
-	_ = w.Flush()
+// FileSizes is the size of each file in CHIK_ROOT
+type FileSizes struct {
+	ChikRoot string     `json:"chik_root" yaml:"chik_root"`
+	Files    []FileInfo `json:"files" yaml:"files"`
 }
 
-// debugFileSizes retrieves the Chik root path and prints sorted file paths with sizes
-func debugFileSizes() {
+// debugFileSizes retrieves the Chik root path and returns file paths with sizes, optionally sorted largest first
+func debugFileSizes() (*FileSizes, error) {
 	chikroot, err := config.GetChikRootPath()
 	if err != nil {
-		fmt.Printf("Could not determine CHIK_ROOT: %s\n", err.Error())
-		return
+		return nil, fmt.Errorf("could not determine CHIK_ROOT: %w", err)
 	}
 
-	fmt.Println("Scanning:", chikroot)
-	fmt.Printf("%-*s %s\n", sizeColumnWidth, "Size", "File") // Header
-	fmt.Println(strings.Repeat("-", 60))                     // Separator
-
 	// Collect files and sort them by size
 	files := collectFiles(chikroot)
 	if viper.GetBool("debug-sort") {
@@ -139,9 +276,16 @@ func debugFileSizes() {
 		})
 	}
 
-	// Print sorted files
-	for _, file := range files {
-		fmt.Printf("%-*s %s\n", sizeColumnWidth, humanReadableSize(file.Size), file.Path)
+	return &FileSizes{ChikRoot: chikroot, Files: files}, nil
+}
+
+// WriteTable writes the files with human readable sizes
+func (f *FileSizes) WriteTable(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "Scanning:", f.ChikRoot)
+	_, _ = fmt.Fprintf(out, "%-*s %s\n", sizeColumnWidth, "Size", "File") // Header
+	_, _ = fmt.Fprintln(out, strings.Repeat("-", 60))                     // Separator
+	for _, file := range f.Files {
+		_, _ = fmt.Fprintf(out, "%-*s %s\n", sizeColumnWidth, humanReadableSize(file.Size), file.Path)
 	}
 }
 
@@ -201,9 +345,13 @@ func humanReadableSize(bytes int64) string {
 func init() {
 	debugCmd.PersistentFlags().Bool("sort", false, "Sort the files largest first")
 	debugCmd.PersistentFlags().Bool("all-files", false, "Show all files. By default, some typically small files are excluded from the output")
+	debugCmd.Flags().String("output", outputTable, "Output format (table, json, yaml)")
+	debugCmd.Flags().StringSlice("sections", nil, "Only include these sections (version, network, ports, rpc, files). Defaults to all sections")
 
 	cobra.CheckErr(viper.BindPFlag("debug-sort", debugCmd.PersistentFlags().Lookup("sort")))
 	cobra.CheckErr(viper.BindPFlag("debug-all-files", debugCmd.PersistentFlags().Lookup("all-files")))
+	cobra.CheckErr(viper.BindPFlag("debug-output", debugCmd.Flags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("debug-sections", debugCmd.Flags().Lookup("sections")))
 
 	cmd.RootCmd.AddCommand(debugCmd)
 }
@@ -0,0 +1,38 @@
+package debug
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSections(t *testing.T) {
+	sections, err := parseSections(nil)
+	assert.NoError(t, err)
+	assert.Len(t, sections, len(allSections))
+
+	sections, err = parseSections([]string{"Ports", " rpc"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{sectionPorts: true, sectionRPC: true}, sections)
+
+	_, err = parseSections([]string{"logs"})
+	assert.Error(t, err)
+}
+
+func TestWriteReportTable(t *testing.T) {
+	out := &bytes.Buffer{}
+	writeReportTable(out, &debugReport{
+		Ports: PortInfo{{Name: "Full Node Port", Port: 8444}},
+		RPC:   RPCStatus{{Service: "Daemon", Running: true}, {Service: "Wallet"}},
+	})
+	assert.Equal(t, "# Port Information\n"+
+		"------------------------------------------------------------\n"+
+		"SERVICE        PORT LISTENING PROCESS STATUS\n"+
+		"Full Node Port 8444 no        -       ok\n"+
+		"\n"+
+		"# RPC Server Status\n"+
+		"------------------------------------------------------------\n"+
+		"Daemon   Running\n"+
+		"Wallet   Not Running\n", out.String())
+}
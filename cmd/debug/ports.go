@@ -0,0 +1,226 @@
+package debug
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-modules/pkg/slogs"
+)
+
+// tcpListenState is the state of a listening socket in /proc/net/tcp
+const tcpListenState = "0A"
+
+// Owners of a port
+const (
+	ownerChik    = "chik"
+	ownerOther   = "other"
+	ownerUnknown = "unknown"
+)
+
+// portOwner is the process holding a listening port
+type portOwner struct {
+	PID     int
+	Process string
+	Cmdline string
+}
+
+// ConfiguredPort is a port configured for a service, and what is listening on it
+type ConfiguredPort struct {
+	Name      string   `json:"name" yaml:"name"`
+	Port      uint16   `json:"port" yaml:"port"`
+	Listening bool     `json:"listening" yaml:"listening"`
+	Owner     string   `json:"owner,omitempty" yaml:"owner,omitempty"`
+	PID       int      `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Process   string   `json:"process,omitempty" yaml:"process,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+
+	// sharedPort is set for ports that are expected to match another service's port, so they aren't a conflict
+	sharedPort bool
+}
+
+// PortInfo is every port configured in the chik config
+type PortInfo []ConfiguredPort
+
+// debugPorts returns the configured ports, whether each is listening and which process holds it
+func debugPorts() (PortInfo, error) {
+	cfg, err := config.GetChikConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %w", err)
+	}
+
+	ports := configuredPorts(cfg)
+
+	owners, err := portOwners()
+	if err != nil {
+		slogs.Logr.Warn("error inspecting listening ports. Checking the configured ports by connecting to them instead", "error", err)
+		owners = nil
+	}
+	probePorts(ports, owners, dialLocalPort)
+	findPortConflicts(ports)
+
+	return ports, nil
+}
+
+// configuredPorts returns every port configured in the chik config
+func configuredPorts(cfg *config.ChikConfig) PortInfo {
+	return PortInfo{
+		{Name: "Full Node Port", Port: cfg.FullNode.Port},
+		{Name: "Full Node RPC", Port: cfg.FullNode.RPCPort},
+		{Name: "Wallet RPC", Port: cfg.Wallet.RPCPort},
+		{Name: "Farmer Port", Port: cfg.Farmer.Port},
+		{Name: "Farmer RPC", Port: cfg.Farmer.RPCPort},
+		{Name: "Harvester RPC", Port: cfg.Harvester.RPCPort},
+		{Name: "Crawler RPC", Port: cfg.Seeder.CrawlerConfig.RPCPort},
+		// The seeder port is the full node port it crawls peers on, and network switch sets it to the full node port
+		{Name: "Seeder Port", Port: cfg.Seeder.Port, sharedPort: true},
+		{Name: "Data Layer Host Port", Port: cfg.DataLayer.HostPort},
+		{Name: "Data Layer RPC", Port: cfg.DataLayer.RPCPort},
+		{Name: "Timelord RPC", Port: cfg.Timelord.RPCPort},
+	}
+}
+
+// probePorts fills in whether each port is listening and who holds it
+// owners comes from the OS when available. Ports not found there are checked by connecting to them
+func probePorts(ports PortInfo, owners map[uint16]portOwner, dial func(uint16) bool) {
+	for i := range ports {
+		port := &ports[i]
+		if port.Port == 0 {
+			continue
+		}
+		if owner, ok := owners[port.Port]; ok {
+			port.Listening = true
+			port.Owner = ownerUnknown
+			if owner.PID != 0 {
+				port.PID = owner.PID
+				port.Process = owner.Process
+				port.Owner = ownerOther
+				if isChikProcess(owner) {
+					port.Owner = ownerChik
+				}
+			}
+			continue
+		}
+		if dial(port.Port) {
+			port.Listening = true
+			port.Owner = ownerUnknown
+		}
+	}
+}
+
+// findPortConflicts records services configured on the same port, and ports held by something other than chik
+// Shared ports are left out of the same port check
+func findPortConflicts(ports PortInfo) {
+	for i := range ports {
+		if ports[i].Port == 0 {
+			continue
+		}
+		for j := range ports {
+			if i != j && ports[i].Port == ports[j].Port && !ports[i].sharedPort && !ports[j].sharedPort {
+				ports[i].Conflicts = append(ports[i].Conflicts, fmt.Sprintf("also configured for %s", ports[j].Name))
+			}
+		}
+		if ports[i].Owner == ownerOther {
+			ports[i].Conflicts = append(ports[i].Conflicts, fmt.Sprintf("held by %s (pid %d), which is not a chik service", ports[i].Process, ports[i].PID))
+		}
+	}
+}
+
+// isChikProcess checks if the process is a chik service, which set their process titles to chik_<service>
+// Otherwise the executable must be chik or chik_<service>, either run directly or by the python interpreter
+func isChikProcess(owner portOwner) bool {
+	if strings.HasPrefix(owner.Process, "chik_") {
+		return true
+	}
+	args := strings.Fields(owner.Cmdline)
+	for i, arg := range args {
+		executable := filepath.Base(arg)
+		if executable == "chik" || strings.HasPrefix(executable, "chik_") {
+			return true
+		}
+		if i > 0 || !strings.HasPrefix(executable, "python") {
+			break
+		}
+	}
+	return false
+}
+
+// dialLocalPort checks if something accepts connections on the port on localhost
+func dialLocalPort(port uint16) bool {
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), 500*time.Millisecond)
+		if err == nil {
+			_ = conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// parseProcNetTCP returns the socket inode and port of every listening socket in /proc/net/tcp or /proc/net/tcp6
+func parseProcNetTCP(content []byte) map[uint64]uint16 {
+	listening := map[uint64]uint16{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		_, portHex, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+		listening[inode] = uint16(port)
+	}
+	return listening
+}
+
+// WriteTable writes the ports as a table, with conflicts highlighted
+func (p PortInfo) WriteTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SERVICE\tPORT\tLISTENING\tPROCESS\tSTATUS")
+	for _, port := range p {
+		listening := "no"
+		if port.Listening {
+			listening = "yes"
+		}
+		process := "-"
+		switch {
+		case port.PID != 0:
+			process = fmt.Sprintf("%s (%d)", port.Process, port.PID)
+		case port.Listening:
+			process = ownerUnknown
+		}
+		status := "ok"
+		if len(port.Conflicts) > 0 {
+			status = "CONFLICT: " + strings.Join(port.Conflicts, "; ")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", port.Name, port.Port, listening, process, status)
+	}
+	_ = w.Flush()
+}
+
+// hasPortConflicts checks if any port has a conflict
+func (p PortInfo) hasPortConflicts() bool {
+	return slices.ContainsFunc(p, func(port ConfiguredPort) bool {
+		return len(port.Conflicts) > 0
+	})
+}
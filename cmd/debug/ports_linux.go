@@ -0,0 +1,72 @@
+//go:build linux
+
+package debug
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portOwners returns the process holding each listening TCP port, from /proc
+// Ports are included with an empty owner when the process can't be read, typically because it belongs to another user
+func portOwners() (map[uint16]portOwner, error) {
+	inodes := map[uint64]uint16{}
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		maps.Copy(inodes, parseProcNetTCP(content))
+	}
+
+	owners := map[uint16]portOwner{}
+	for _, port := range inodes {
+		owners[port] = portOwner{}
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join("/proc", proc.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join("/proc", proc.Name(), "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			port, ok := inodes[inode]
+			if !ok {
+				continue
+			}
+			comm, _ := os.ReadFile(filepath.Join("/proc", proc.Name(), "comm"))
+			cmdline, _ := os.ReadFile(filepath.Join("/proc", proc.Name(), "cmdline"))
+			owners[port] = portOwner{
+				PID:     pid,
+				Process: strings.TrimSpace(string(comm)),
+				Cmdline: strings.ReplaceAll(string(cmdline), "\x00", " "),
+			}
+		}
+	}
+
+	return owners, nil
+}
@@ -0,0 +1,90 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcNetTCP(t *testing.T) {
+	content := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:25CE 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1111 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:D1D2 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 2222 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:D1D2 0100007F:9C40 01 00000000:00000000 00:00000000 00000000  1000        0 3333 1 0000000000000000 20 4 30 10 -1
+`)
+	assert.Equal(t, map[uint64]uint16{1111: 9678, 2222: 53714}, parseProcNetTCP(content))
+}
+
+func TestProbePorts(t *testing.T) {
+	ports := PortInfo{
+		{Name: "Full Node Port", Port: 9678},
+		{Name: "Full Node RPC", Port: 8555},
+		{Name: "Wallet RPC", Port: 9256},
+		{Name: "Farmer Port", Port: 8447},
+		{Name: "Data Layer RPC", Port: 8555},
+		{Name: "Timelord RPC", Port: 8557},
+	}
+	owners := map[uint16]portOwner{
+		9678: {PID: 10, Process: "chik_full_node"},
+		8555: {PID: 11, Process: "nginx"},
+		9256: {},
+	}
+	probePorts(ports, owners, func(port uint16) bool { return port == 8447 })
+	findPortConflicts(ports)
+
+	assert.Equal(t, ConfiguredPort{Name: "Full Node Port", Port: 9678, Listening: true, Owner: ownerChik, PID: 10, Process: "chik_full_node"}, ports[0])
+	assert.Equal(t, []string{
+		"also configured for Data Layer RPC",
+		"held by nginx (pid 11), which is not a chik service",
+	}, ports[1].Conflicts)
+	assert.Equal(t, ConfiguredPort{Name: "Wallet RPC", Port: 9256, Listening: true, Owner: ownerUnknown}, ports[2])
+	assert.Equal(t, ConfiguredPort{Name: "Farmer Port", Port: 8447, Listening: true, Owner: ownerUnknown}, ports[3])
+	assert.Equal(t, []string{
+		"also configured for Full Node RPC",
+		"held by nginx (pid 11), which is not a chik service",
+	}, ports[4].Conflicts)
+	assert.Equal(t, ConfiguredPort{Name: "Timelord RPC", Port: 8557}, ports[5])
+	assert.True(t, ports.hasPortConflicts())
+}
+
+func TestFindPortConflicts_DefaultConfig(t *testing.T) {
+	cfg, err := config.LoadDefaultConfig()
+	assert.NoError(t, err)
+	// network switch sets the seeder port to the full node port
+	cfg.Seeder.Port = cfg.FullNode.Port
+
+	ports := configuredPorts(cfg)
+	findPortConflicts(ports)
+	for _, port := range ports {
+		assert.Empty(t, port.Conflicts, port.Name)
+	}
+	assert.False(t, ports.hasPortConflicts())
+
+	// Services that really share a port are still a conflict
+	cfg.DataLayer.RPCPort = cfg.FullNode.RPCPort
+	ports = configuredPorts(cfg)
+	findPortConflicts(ports)
+	assert.True(t, ports.hasPortConflicts())
+}
+
+func TestIsChikProcess(t *testing.T) {
+	for _, owner := range []portOwner{
+		{Process: "chik_full_node"},
+		{Process: "chik_data_layer", Cmdline: "chik_data_layer"},
+		{Process: "chik", Cmdline: "/opt/chik/venv/bin/chik run_daemon"},
+		{Process: "python3", Cmdline: "/usr/bin/python3 /opt/chik/venv/bin/chik_harvester"},
+	} {
+		assert.True(t, isChikProcess(owner), owner)
+	}
+
+	for _, owner := range []portOwner{
+		{Process: "nginx", Cmdline: "nginx -c /etc/nginx/chik-mirror.conf"},
+		{Process: "chikexplorer", Cmdline: "/usr/local/bin/chikexplorer"},
+		{Process: "python3", Cmdline: "python3 -m http.server --directory /home/chik/.chik/mainnet/data_layer"},
+		{Process: "node", Cmdline: "node /srv/tools/chik_indexer.js"},
+		{},
+	} {
+		assert.False(t, isChikProcess(owner), owner)
+	}
+}
@@ -4,35 +4,46 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"text/tabwriter"
 
 	"github.com/chik-network/go-chik-libs/pkg/rpc"
 	"github.com/chik-network/go-modules/pkg/slogs"
 )
 
-// ShowVersionInfo outputs the running version for all services
-func ShowVersionInfo() {
-	slogs.Logr.Debug("initializing websocket client")
-	websocketClient, err := rpc.NewClient(rpc.ConnectionModeWebsocket, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
+// ServiceVersion is the running version of a single service
+type ServiceVersion struct {
+	Service string `json:"service" yaml:"service"`
+	Running bool   `json:"running" yaml:"running"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// VersionInfo is the running version of every service
+type VersionInfo []ServiceVersion
+
+// ShowVersionInfo returns the running version for all services
+func ShowVersionInfo() (VersionInfo, error) {
+	services, err := debugServices()
 	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
+		return nil, err
 	}
-	slogs.Logr.Debug("initializing http client")
-	rpcClient, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
-	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
+
+	var info VersionInfo
+	for _, s := range services {
+		info = append(info, versionHelper(s.service, s.label))
 	}
+	return info, nil
+}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	versionHelper(w, websocketClient.DaemonService, "Daemon")
-	versionHelper(w, rpcClient.FullNodeService, "Full Node")
-	versionHelper(w, rpcClient.WalletService, "Wallet")
-	versionHelper(w, rpcClient.FarmerService, "Farmer")
-	versionHelper(w, rpcClient.HarvesterService, "Harvester")
-	versionHelper(w, rpcClient.CrawlerService, "Crawler")
-	versionHelper(w, rpcClient.DataLayerService, "Data Layer")
-	versionHelper(w, rpcClient.TimelordService, "Timelord")
+// WriteTable writes the versions as a table
+func (v VersionInfo) WriteTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	for _, service := range v {
+		version := service.Version
+		if !service.Running {
+			version = "Not Running"
+		}
+		_, _ = fmt.Fprintln(w, service.Service, "\t", version)
+	}
 	_ = w.Flush()
 }
 
@@ -40,15 +51,46 @@ type hasVersionInfo interface {
 	GetVersion(opts *rpc.GetVersionOptions) (*rpc.GetVersionResponse, *http.Response, error)
 }
 
-func versionHelper(w io.Writer, service hasVersionInfo, label string) {
+// debugService is a service checked by the debug sections
+type debugService struct {
+	label   string
+	service hasVersionInfo
+}
+
+// debugServices returns every chik service, in the order they are shown in the debug output
+func debugServices() ([]debugService, error) {
+	slogs.Logr.Debug("initializing websocket client")
+	websocketClient, err := rpc.NewClient(rpc.ConnectionModeWebsocket, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
+	if err != nil {
+		return nil, fmt.Errorf("error initializing websocket RPC client: %w", err)
+	}
+	slogs.Logr.Debug("initializing http client")
+	rpcClient, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
+	if err != nil {
+		return nil, fmt.Errorf("error initializing http RPC client: %w", err)
+	}
+
+	return []debugService{
+		{"Daemon", websocketClient.DaemonService},
+		{"Full Node", rpcClient.FullNodeService},
+		{"Wallet", rpcClient.WalletService},
+		{"Farmer", rpcClient.FarmerService},
+		{"Harvester", rpcClient.HarvesterService},
+		{"Crawler", rpcClient.CrawlerService},
+		{"Data Layer", rpcClient.DataLayerService},
+		{"Timelord", rpcClient.TimelordService},
+	}, nil
+}
+
+func versionHelper(service hasVersionInfo, label string) ServiceVersion {
 	version, _, err := service.GetVersion(&rpc.GetVersionOptions{})
 	if err != nil {
-		slogs.Logr.Debug("error getting network info from daemon", "error", err)
-		version = &rpc.GetVersionResponse{Version: "Not Running"}
+		slogs.Logr.Debug("error getting version info", "service", label, "error", err)
+		return ServiceVersion{Service: label}
 	}
 	if version == nil {
-		slogs.Logr.Debug("no network info found", "service", label)
-		version = &rpc.GetVersionResponse{Version: "Not Running"}
+		slogs.Logr.Debug("no version info found", "service", label)
+		return ServiceVersion{Service: label}
 	}
-	_, _ = fmt.Fprintln(w, label, "\t", version.Version)
+	return ServiceVersion{Service: label, Running: true, Version: version.Version}
 }
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/chik-network/go-chik-libs/pkg/config"
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/cobra"
+)
+
+// NetworkDefinition is a network defined in network_overrides in the chik config
+type NetworkDefinition struct {
+	Name                string `json:"name"`
+	Selected            bool   `json:"selected"`
+	AddressPrefix       string `json:"address_prefix"`
+	DefaultFullNodePort uint16 `json:"default_full_node_port"`
+	GenesisChallenge    string `json:"genesis_challenge"`
+}
+
+// listCmd lists the networks defined in the config
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the networks defined in the config, marking the selected network",
+	Run: func(cmd *cobra.Command, args []string) {
+		networks, err := ListNetworks()
+		if err != nil {
+			slogs.Logr.Fatal("error listing networks", "error", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NETWORK\tSELECTED\tPREFIX\tPORT\tGENESIS CHALLENGE")
+		for _, n := range networks {
+			selected := ""
+			if n.Selected {
+				selected = "*"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", n.Name, selected, n.AddressPrefix, n.DefaultFullNodePort, n.GenesisChallenge)
+		}
+		_ = w.Flush()
+	},
+}
+
+// ListNetworks returns every network in network_overrides, sorted by name
+func ListNetworks() ([]NetworkDefinition, error) {
+	cfg, err := config.GetChikConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+
+	selected := ""
+	if cfg.SelectedNetwork != nil {
+		selected = *cfg.SelectedNetwork
+	}
+
+	names := map[string]bool{}
+	for name := range cfg.NetworkOverrides.Constants {
+		names[name] = true
+	}
+	for name := range cfg.NetworkOverrides.Config {
+		names[name] = true
+	}
+
+	var networks []NetworkDefinition
+	for name := range names {
+		networks = append(networks, NetworkDefinition{
+			Name:                name,
+			Selected:            name == selected,
+			AddressPrefix:       cfg.NetworkOverrides.Config[name].AddressPrefix,
+			DefaultFullNodePort: cfg.NetworkOverrides.Config[name].DefaultFullNodePort,
+			GenesisChallenge:    cfg.NetworkOverrides.Constants[name].GenesisChallenge,
+		})
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+	return networks, nil
+}
+
+func init() {
+	networkCmd.AddCommand(listCmd)
+}
@@ -18,48 +18,77 @@ var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show information about the currently selected/running network",
 	Run: func(cmd *cobra.Command, args []string) {
-		ShowNetworkInfo()
+		info, err := ShowNetworkInfo()
+		if err != nil {
+			slogs.Logr.Fatal("error getting network info", "error", err)
+		}
+		info.WriteTable(os.Stdout)
 	},
 }
 
-// ShowNetworkInfo outputs network information from the configuration and any running services
-func ShowNetworkInfo() {
+// ServiceNetwork is the network a single service is running on
+type ServiceNetwork struct {
+	Service string `json:"service" yaml:"service"`
+	Running bool   `json:"running" yaml:"running"`
+	Network string `json:"network" yaml:"network"`
+}
+
+// NetworkInfo is the network selected in the configuration and the network of each running service
+type NetworkInfo struct {
+	Config   string           `json:"config" yaml:"config"`
+	Services []ServiceNetwork `json:"services" yaml:"services"`
+}
+
+// ShowNetworkInfo returns network information from the configuration and any running services
+func ShowNetworkInfo() (*NetworkInfo, error) {
 	chikRoot, err := config.GetChikRootPath()
 	if err != nil {
-		slogs.Logr.Fatal("error determining chik root", "error", err)
+		return nil, fmt.Errorf("error determining chik root: %w", err)
 	}
 	slogs.Logr.Debug("Chik root discovered", "CHIK_ROOT", chikRoot)
 
 	cfg, err := config.GetChikConfig()
 	if err != nil {
-		slogs.Logr.Fatal("error loading config", "error", err)
+		return nil, fmt.Errorf("error loading config: %w", err)
 	}
 	slogs.Logr.Debug("Successfully loaded config")
 
-	configNetwork := *cfg.SelectedNetwork
-
 	slogs.Logr.Debug("initializing websocket client")
 	websocketClient, err := rpc.NewClient(rpc.ConnectionModeWebsocket, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
 	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
+		return nil, fmt.Errorf("error initializing websocket RPC client: %w", err)
 	}
 	slogs.Logr.Debug("initializing http client")
 	rpcClient, err := rpc.NewClient(rpc.ConnectionModeHTTP, rpc.WithAutoConfig(), rpc.WithSyncWebsocket())
 	if err != nil {
-		slogs.Logr.Fatal("error initializing websocket RPC client", "error", err)
+		return nil, fmt.Errorf("error initializing http RPC client: %w", err)
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	_, _ = fmt.Fprintln(w, "Config\t", configNetwork)
+	info := &NetworkInfo{Config: *cfg.SelectedNetwork}
+	info.Services = append(info.Services,
+		networkHelper(websocketClient.DaemonService, "Daemon"),
+		networkHelper(rpcClient.FullNodeService, "Full Node"),
+		networkHelper(rpcClient.WalletService, "Wallet"),
+		networkHelper(rpcClient.FarmerService, "Farmer"),
+		networkHelper(rpcClient.HarvesterService, "Harvester"),
+		networkHelper(rpcClient.CrawlerService, "Crawler"),
+		networkHelper(rpcClient.DataLayerService, "Data Layer"),
+		networkHelper(rpcClient.TimelordService, "Timelord"),
+	)
+	return info, nil
+}
 
-	networkHelper(w, websocketClient.DaemonService, "Daemon")
-	networkHelper(w, rpcClient.FullNodeService, "Full Node")
-	networkHelper(w, rpcClient.WalletService, "Wallet")
-	networkHelper(w, rpcClient.FarmerService, "Farmer")
-	networkHelper(w, rpcClient.HarvesterService, "Harvester")
-	networkHelper(w, rpcClient.CrawlerService, "Crawler")
-	networkHelper(w, rpcClient.DataLayerService, "Data Layer")
-	networkHelper(w, rpcClient.TimelordService, "Timelord")
+// WriteTable writes the network information as a table
+func (n *NetworkInfo) WriteTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Config\t", n.Config)
+	for _, service := range n.Services {
+		network := service.Network
+		if !service.Running {
+			network = "Not Running"
+		}
+		_, _ = fmt.Fprintln(w, service.Service, "\t", network)
+	}
 	_ = w.Flush()
 }
 
@@ -67,17 +96,18 @@ type hasNetworkName interface {
 	GetNetworkInfo(opts *rpc.GetNetworkInfoOptions) (*rpc.GetNetworkInfoResponse, *http.Response, error)
 }
 
-func networkHelper(w io.Writer, service hasNetworkName, label string) {
+func networkHelper(service hasNetworkName, label string) ServiceNetwork {
 	network, _, err := service.GetNetworkInfo(&rpc.GetNetworkInfoOptions{})
 	if err != nil {
-		slogs.Logr.Debug("error getting network info from daemon", "error", err)
-		network = &rpc.GetNetworkInfoResponse{}
+		slogs.Logr.Debug("error getting network info", "service", label, "error", err)
+		return ServiceNetwork{Service: label}
 	}
 	if network == nil {
 		slogs.Logr.Debug("no network info found", "service", label)
-		network = &rpc.GetNetworkInfoResponse{}
+		return ServiceNetwork{Service: label}
 	}
-	_, _ = fmt.Fprintln(w, label, "\t", network.NetworkName.OrElse("Not Running"))
+	name, ok := network.NetworkName.Get()
+	return ServiceNetwork{Service: label, Running: ok, Network: name}
 }
 
 func init() {
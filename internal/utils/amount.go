@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MojosPerXCK is the number of mojos in one XCK
+const MojosPerXCK = 1000000000000
+
+// xckDecimals is the number of decimal places that can be represented in XCK
+const xckDecimals = 12
+
+// AmountUnit is the unit used for amounts that don't have an explicit suffix
+type AmountUnit int
+
+const (
+	// UnitXCK treats bare numbers as XCK
+	UnitXCK AmountUnit = iota
+	// UnitMojo treats bare numbers as mojos
+	UnitMojo
+)
+
+// ParseAmount parses an amount into mojos without any floating point conversion
+// Amounts may end in "xck" or "mojo"/"mojos" to set the unit explicitly, otherwise defaultUnit is used
+// Examples: "0.00001", "0.00001xck", "100mojos"
+func ParseAmount(value string, defaultUnit AmountUnit) (uint64, error) {
+	amount := strings.ToLower(strings.TrimSpace(value))
+	unit := defaultUnit
+	for _, suffix := range []string{"mojos", "mojo"} {
+		if strings.HasSuffix(amount, suffix) {
+			amount = strings.TrimSpace(strings.TrimSuffix(amount, suffix))
+			unit = UnitMojo
+			break
+		}
+	}
+	if strings.HasSuffix(amount, "xck") {
+		amount = strings.TrimSpace(strings.TrimSuffix(amount, "xck"))
+		unit = UnitXCK
+	}
+	if amount == "" {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	if unit == UnitMojo {
+		mojos, err := strconv.ParseUint(amount, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid mojo amount %q: must be a whole number of mojos", value)
+		}
+		return mojos, nil
+	}
+
+	whole, frac, _ := strings.Cut(amount, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid XCK amount %q: no digits", value)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > xckDecimals {
+		return 0, fmt.Errorf("invalid amount %q: XCK amounts can have at most %d decimal places", value, xckDecimals)
+	}
+	digits := whole + frac + strings.Repeat("0", xckDecimals-len(frac))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid XCK amount %q", value)
+		}
+	}
+
+	mojos, ok := new(big.Int).SetString(digits, 10)
+	if !ok || !mojos.IsUint64() {
+		return 0, fmt.Errorf("invalid XCK amount %q: out of range", value)
+	}
+	return mojos.Uint64(), nil
+}
+
+// FormatXCK formats a mojo amount as an exact XCK decimal string
+func FormatXCK(mojos uint64) string {
+	whole := mojos / MojosPerXCK
+	frac := mojos % MojosPerXCK
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%012d", frac), "0")
+	return fmt.Sprintf("%d.%s", whole, fracStr)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value       string
+		defaultUnit AmountUnit
+		expected    uint64
+	}{
+		{"0", UnitXCK, 0},
+		{"1", UnitXCK, 1000000000000},
+		{"0.00000001", UnitXCK, 10000},
+		{"0.000000000001", UnitXCK, 1},
+		{".5", UnitXCK, 500000000000},
+		{"1.1xck", UnitMojo, 1100000000000},
+		{"300", UnitMojo, 300},
+		{"300 mojos", UnitXCK, 300},
+		{"1mojo", UnitXCK, 1},
+	}
+	for _, tt := range tests {
+		actual, err := ParseAmount(tt.value, tt.defaultUnit)
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, actual, tt.value)
+	}
+
+	for _, invalid := range []string{"", "abc", "-1", "0.0000000000001", "1.5mojos", "1e3", "99999999xck", ".", " . xck", "mojos"} {
+		_, err := ParseAmount(invalid, UnitXCK)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFormatXCK(t *testing.T) {
+	assert.Equal(t, "0", FormatXCK(0))
+	assert.Equal(t, "1", FormatXCK(1000000000000))
+	assert.Equal(t, "0.00000001", FormatXCK(10000))
+	assert.Equal(t, "1.000000000001", FormatXCK(1000000000001))
+}
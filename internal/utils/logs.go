@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/viper"
+)
+
+// LogToStderr moves the logger to stderr, for commands that write machine readable output to stdout
+// The level is read from the same log-level setting cmd.InitLogs uses, with the same names slogs accepts
+func LogToStderr() {
+	logLevel := viper.GetString("log-level")
+	if strings.EqualFold(logLevel, "warning") {
+		logLevel = "warn"
+	}
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	slogs.Logr = slogs.Logger{Logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))}
+}
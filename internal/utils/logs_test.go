@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/chik-network/go-modules/pkg/slogs"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogToStderr(t *testing.T) {
+	defer viper.Reset()
+	for level, expected := range map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"info":    slog.LevelInfo,
+		"WARNING": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"unknown": slog.LevelInfo,
+	} {
+		viper.Set("log-level", level)
+		LogToStderr()
+		assert.True(t, slogs.Logr.Enabled(context.Background(), expected), level)
+		assert.False(t, slogs.Logr.Enabled(context.Background(), expected-1), level)
+	}
+}